@@ -3,7 +3,6 @@ module github.com/josa42/nvim-filetree
 go 1.16
 
 require (
-	github.com/josa42/go-gitignore v1.0.0
 	github.com/josa42/go-neovim v0.3.1
 )
 
@@ -8,6 +8,35 @@ const (
 	OpenTab             = "open-tab"
 	OpenVerticalSplit   = "open-vertical-split"
 	OpenHorizontalSplit = "open-horizontal-split"
+	New                 = "new"
+	Rename              = "rename"
+	Delete              = "delete"
+	Cut                 = "cut"
+	Copy                = "copy"
+	Paste               = "paste"
+	ToggleMark          = "toggle-mark"
+	ClearMarks          = "clear-marks"
+	Quickfix            = "quickfix"
+	Restore             = "restore"
+	Stage               = "stage"
+	Unstage             = "unstage"
+	Discard             = "discard"
+	Diff                = "diff"
+	DiffSplit           = "diff-split"
+	ToggleHidden        = "toggle-hidden"
+	ToggleIgnored       = "toggle-ignored"
+	Filter              = "filter"
+	RootToItem          = "root-to-item"
+	RootUp              = "root-up"
+	RootToCwd           = "root-to-cwd"
+	Bookmark            = "bookmark"
+	JumpToBookmark      = "jump-to-bookmark"
+	RemoveBookmark      = "remove-bookmark"
+	CycleSort           = "cycle-sort"
+	ToggleDirsFirst     = "toggle-dirs-first"
+	ToggleDetails       = "toggle-details"
+	Undo                = "undo"
+	Redo                = "redo"
 	Unfocus             = "unfocus"
 	Help                = "help"
 )
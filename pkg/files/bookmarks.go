@@ -0,0 +1,113 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/josa42/nvim-filetree/pkg/opener"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+const bookmarksFile = "bookmarks.json"
+
+type bookmark struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func (p *FileProvider) loadBookmarks() []bookmark {
+	bookmarks := []bookmark{}
+	readJSON(p.dataPath(bookmarksFile), &bookmarks)
+
+	return bookmarks
+}
+
+func (p *FileProvider) saveBookmarks(bookmarks []bookmark) {
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].Name < bookmarks[j].Name
+	})
+
+	if err := writeJSON(p.dataPath(bookmarksFile), bookmarks); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not save bookmarks: %v", err))
+	}
+}
+
+func (p *FileProvider) addBookmark(i *FileItem) {
+	name, ok := prompt.Input(p.api, "Bookmark name: ", filepath.Base(i.path))
+	if !ok {
+		return
+	}
+
+	bookmarks := []bookmark{{Name: name, Path: i.path}}
+	for _, b := range p.loadBookmarks() {
+		if b.Name != name {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+
+	p.saveBookmarks(bookmarks)
+	p.api.Out.Print(fmt.Sprintf("Bookmarked %s as %s", p.relPath(i.path), name))
+}
+
+// selectBookmark lists all bookmarks and returns the chosen one.
+func (p *FileProvider) selectBookmark(title string) (bookmark, []bookmark, bool) {
+	bookmarks := p.loadBookmarks()
+	if len(bookmarks) == 0 {
+		p.api.Out.Print("No bookmarks")
+		return bookmark{}, bookmarks, false
+	}
+
+	items := make([]string, len(bookmarks))
+	for idx, b := range bookmarks {
+		items[idx] = fmt.Sprintf("%-20s %s", b.Name, b.Path)
+	}
+
+	choice := prompt.Select(p.api, title, items)
+	if choice < 1 || choice > len(bookmarks) {
+		return bookmark{}, bookmarks, false
+	}
+
+	return bookmarks[choice-1], bookmarks, true
+}
+
+// jumpToBookmark makes a bookmarked directory the root. Bookmarked files are
+// revealed, with their directory as root if they are outside of the tree.
+func (p *FileProvider) jumpToBookmark() {
+	b, _, ok := p.selectBookmark("Jump to bookmark:")
+	if !ok {
+		return
+	}
+
+	switch {
+	case !exists(b.Path):
+		p.api.Out.Print(fmt.Sprintf("%s does not exist anymore", b.Path))
+
+	case isDir(b.Path):
+		p.changeRoot(b.Path)
+
+	default:
+		if !isBelow(b.Path, p.root.path) {
+			p.changeRoot(filepath.Dir(b.Path))
+		}
+		p.reveal(b.Path)
+		opener.Activate(p.api, b.Path)
+	}
+}
+
+func (p *FileProvider) removeBookmark() {
+	b, bookmarks, ok := p.selectBookmark("Remove bookmark:")
+	if !ok {
+		return
+	}
+
+	kept := []bookmark{}
+	for _, other := range bookmarks {
+		if other.Name != b.Name {
+			kept = append(kept, other)
+		}
+	}
+
+	p.saveBookmarks(kept)
+	p.api.Out.Print(fmt.Sprintf("Removed bookmark %s", b.Name))
+}
@@ -0,0 +1,203 @@
+package files
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/josa42/nvim-filetree/pkg/opener"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+type clipboard struct {
+	paths []string
+	cut   bool
+}
+
+func (p *FileProvider) copyToClipboard(items []*FileItem, cut bool) {
+	paths := []string{}
+	for _, i := range topLevelItems(items) {
+		if i != p.root {
+			paths = append(paths, i.path)
+		}
+	}
+
+	p.clipboard = clipboard{paths: paths, cut: cut}
+	p.clearMarks()
+
+	what := fmt.Sprintf("%d items", len(paths))
+	if len(paths) == 1 {
+		what = p.relPath(paths[0])
+	}
+
+	if cut {
+		p.api.Out.Print(fmt.Sprintf("Cut %s", what))
+	} else {
+		p.api.Out.Print(fmt.Sprintf("Copied %s", what))
+	}
+}
+
+func (p *FileProvider) paste(i *FileItem) {
+	if len(p.clipboard.paths) == 0 {
+		p.api.Out.Print("Clipboard is empty")
+		return
+	}
+
+	dir := filepath.Dir(i.path)
+	if i.isDir {
+		dir = i.path
+	}
+
+	last := ""
+
+	for _, path := range p.clipboard.paths {
+		target := filepath.Join(dir, filepath.Base(path))
+
+		if isBelow(target, path) {
+			p.api.Out.Print(fmt.Sprintf("Cannot paste %s into itself", p.relPath(path)))
+			continue
+		}
+
+		if target == path {
+			if p.clipboard.cut {
+				continue
+			}
+			target = uniquePath(target)
+		}
+
+		if exists(target) {
+			choice := prompt.Choose(p.api, fmt.Sprintf("%s already exists", p.relPath(target)), []string{"&Overwrite", "&Skip", "&Rename"}, 2)
+			switch choice {
+			case 1:
+				if isBelow(path, target) {
+					p.api.Out.Print(fmt.Sprintf("Cannot overwrite %s with its own content", p.relPath(target)))
+					continue
+				}
+				trashPath, err := trash(target)
+				if err != nil {
+					p.api.Out.Print(fmt.Sprintf("Could not trash %s: %v", p.relPath(target), err))
+					continue
+				}
+				p.journal.record(operation{kind: operationTrash, path: target, target: trashPath})
+				opener.WipeBuffers(p.api, target)
+			case 3:
+				target = uniquePath(target)
+			default:
+				continue
+			}
+		}
+
+		if err := p.pastePath(path, target); err != nil {
+			p.api.Out.Print(fmt.Sprintf("Could not paste %s: %v", p.relPath(path), err))
+			continue
+		}
+
+		last = target
+	}
+
+	if p.clipboard.cut {
+		p.clipboard = clipboard{}
+	}
+
+	if last != "" {
+		p.reveal(last)
+	}
+}
+
+func (p *FileProvider) pastePath(path, target string) error {
+	if !p.clipboard.cut {
+		if err := copyPath(path, target); err != nil {
+			return err
+		}
+
+		p.journal.record(operation{kind: operationCopy, path: path, target: target})
+		return nil
+	}
+
+	item, found := p.lookup(path)
+
+	if err := movePath(path, target); err != nil {
+		return err
+	}
+
+	p.journal.record(operation{kind: operationMove, path: path, target: target})
+
+	opener.RenameBuffers(p.api, path, target)
+	if found {
+		p.relocate(item, target)
+	}
+
+	return nil
+}
+
+// uniquePath returns the first path of the form "name_<n>.ext" that does not
+// exist yet.
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 1; ; n++ {
+		p := fmt.Sprintf("%s_%d%s", base, n, ext)
+		if !exists(p) {
+			return p
+		}
+	}
+}
+
+func copyPath(path, target string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+
+	case fi.IsDir():
+		if err := os.MkdirAll(target, fi.Mode().Perm()); err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if err := copyPath(filepath.Join(path, e.Name()), filepath.Join(target, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return copyFile(path, target, fi.Mode().Perm())
+	}
+}
+
+func copyFile(path, target string, perm os.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
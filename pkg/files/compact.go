@@ -0,0 +1,115 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/josa42/go-neovim/view"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+// GlobalVarCompactDirs disables the compaction of directory chains if set to
+// 0.
+const GlobalVarCompactDirs = "tree_compact_dirs"
+
+func (p *FileProvider) loadCompactDirs() bool {
+	compact := 1
+	p.api.Eval(fmt.Sprintf("get(g:, %s, 1)", prompt.Quote(GlobalVarCompactDirs)), &compact)
+
+	return compact != 0
+}
+
+// compact returns the deepest directory of the chain of directories starting
+// at i that each contain nothing but a single directory. The chain is shown as
+// a single item, opening or closing it toggles the deepest directory.
+//
+// Chains are built from the unfiltered directory contents, so filtering never
+// creates a chain. The directories inside a chain are only reported as open
+// by IsOpen(), their stored state is not changed.
+func (i *FileItem) compact() *FileItem {
+	i.chainHead = ""
+	i.inChain = false
+	if !i.provider.compactDirs {
+		return i
+	}
+
+	item := i
+	for item.isDir && !item.isGhost && !item.isSymlink() {
+		name, ok := item.onlyEntry()
+		if !ok || len(item.provider.fileStatus.deleted(item.path)) > 0 {
+			break
+		}
+
+		next := item.child(name)
+		if _, visible := item.provider.visibility(next.path, next.isDir, false); !next.isDir || !visible {
+			break
+		}
+
+		// The directory has no other entries
+		item.children = []view.TreeItem{next}
+
+		item.inChain = true
+		item = next
+		item.inChain = false
+	}
+
+	if item != i {
+		item.chainHead = i.path
+	}
+
+	return item
+}
+
+// entryCache holds the only entry of a directory, valid as long as the
+// modification time of the directory does not change.
+type entryCache struct {
+	modTime time.Time
+	only    string
+}
+
+// onlyEntry returns the name of the entry of the directory, if it has exactly
+// one. The directory is only read again once it changed.
+func (i *FileItem) onlyEntry() (string, bool) {
+	fi, err := os.Stat(i.path)
+	if err != nil {
+		return "", false
+	}
+
+	if i.entries == nil || !i.entries.modTime.Equal(fi.ModTime()) {
+		only := ""
+		if names := childrenNames(i.path); len(names) == 1 {
+			only = names[0]
+		}
+		i.entries = &entryCache{modTime: fi.ModTime(), only: only}
+	}
+
+	return i.entries.only, i.entries.only != ""
+}
+
+func (i *FileItem) isSymlink() bool {
+	fi := i.stat()
+	return fi != nil && fi.Mode()&os.ModeSymlink != 0
+}
+
+// displayName returns the name of the item, or the names of all directories
+// in its chain if it is compacted.
+func (i *FileItem) displayName() string {
+	if i.chainHead != "" {
+		if name, err := filepath.Rel(filepath.Dir(i.chainHead), i.path); err == nil {
+			return name
+		}
+	}
+	return i.name
+}
+
+// shows reports whether path is shown by the item, either as the item itself
+// or as part of its compacted chain.
+func (i *FileItem) shows(path string) bool {
+	if i.path == path {
+		return true
+	}
+
+	return i.chainHead != "" && (i.chainHead == path || isBelow(path, i.chainHead)) && isBelow(i.path, path)
+}
@@ -0,0 +1,152 @@
+package files
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// GlobalVarDetails enables the details columns when the tree is created.
+const GlobalVarDetails = "tree_details"
+
+// detailsGap is the minimal space between the name and the details columns.
+const detailsGap = 2
+
+// updateDetailsWidth reads the width of the tree window, the details columns
+// are aligned to its right edge.
+func (p *FileProvider) updateDetailsWidth() {
+	if !p.showDetails {
+		return
+	}
+
+	width := 0
+	p.api.Eval("winwidth(bufwinid(g:tree_buffer_id))", &width)
+	p.detailsWidth = width
+}
+
+// withDetails appends the size, modification time and permissions of item to
+// its rendered text.
+func (p *FileProvider) withDetails(item *FileItem, text string) string {
+	fi := item.stat()
+	if fi == nil {
+		return text
+	}
+
+	sizeColumn := "-"
+	if !fi.IsDir() {
+		sizeColumn = humanSize(fi.Size())
+	}
+
+	details := fmt.Sprintf("%5s  %4s  %s", sizeColumn, relativeTime(fi.ModTime(), time.Now()), permissions(fi.Mode()))
+
+	// Indentation, status and the space after it are rendered before the text
+	used := item.depth*2 + 2 + displayWidth(text) + displayWidth(details)
+	gap := p.detailsWidth - used
+	if gap < detailsGap {
+		gap = detailsGap
+	}
+
+	return text + strings.Repeat(" ", gap) + details
+}
+
+func humanSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	// The value is rounded before the unit is picked, so that e.g. 1048575
+	// becomes "1.0M" rather than "1024K"
+	value := float64(size)
+	for _, unit := range []string{"K", "M", "G", "T"} {
+		value /= 1024
+		if math.Round(value*10) < 100 {
+			return fmt.Sprintf("%.1f%s", value, unit)
+		}
+		if math.Round(value) < 1024 || unit == "T" {
+			return fmt.Sprintf("%.0f%s", value, unit)
+		}
+	}
+
+	return ""
+}
+
+// wideRanges are the East Asian wide and fullwidth ranges, which take two
+// cells in the terminal.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x3fffd},
+}
+
+// displayWidth returns the number of cells s takes in the terminal, like
+// strdisplaywidth() with the default 'ambiwidth'.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case isWide(r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+func isWide(r rune) bool {
+	for _, rng := range wideRanges {
+		if r >= rng.lo && r <= rng.hi {
+			return true
+		}
+	}
+	return false
+}
+
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo", d/(30*24*time.Hour))
+	default:
+		return fmt.Sprintf("%dy", d/(365*24*time.Hour))
+	}
+}
+
+// permissions returns the mode in the format of ls, e.g. "drwxr-xr-x".
+func permissions(mode os.FileMode) string {
+	kind := "-"
+	switch {
+	case mode&os.ModeSymlink != 0:
+		kind = "l"
+	case mode.IsDir():
+		kind = "d"
+	}
+
+	return kind + mode.Perm().String()[1:]
+}
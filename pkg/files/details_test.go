@@ -0,0 +1,44 @@
+package files
+
+import "testing"
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{10188, "9.9K"},
+		{10239, "10K"},
+		{1048575, "1.0M"},
+		{10 * 1024 * 1024, "10M"},
+		{1024 * 1024 * 1024 * 1024 * 2048, "2048T"},
+	}
+
+	for _, test := range tests {
+		if got := humanSize(test.size); got != test.want {
+			t.Errorf("humanSize(%d) = %q, want %q", test.size, got, test.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"main.go", 7},
+		{"ünïcødé.txt", 11},
+		{"u\u0308.txt", 5},
+		{"日本語.txt", 10},
+		{"한글", 4},
+	}
+
+	for _, test := range tests {
+		if got := displayWidth(test.text); got != test.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
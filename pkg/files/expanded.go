@@ -0,0 +1,70 @@
+package files
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+)
+
+const expandedFile = "expanded.json"
+
+// loadOpenDirs reads the directories that were open the last time path was
+// the root.
+func (p *FileProvider) loadOpenDirs(root string) map[string]bool {
+	dirs := map[string]bool{}
+
+	expanded := map[string][]string{}
+	readJSON(p.dataPath(expandedFile), &expanded)
+
+	for _, rel := range expanded[root] {
+		dirs[filepath.Join(root, rel)] = true
+	}
+
+	return dirs
+}
+
+// SaveOpenDirs stores the open directories of the current root.
+func (p *FileProvider) SaveOpenDirs() {
+	if p.root.path == "" {
+		return
+	}
+
+	dirs := []string{}
+
+	var walk func(i *FileItem)
+	walk = func(i *FileItem) {
+		for _, c := range i.children {
+			if child, ok := c.(*FileItem); ok && child.isDir {
+				if child.isOpen {
+					dirs = append(dirs, p.relPath(child.path))
+				}
+				walk(child)
+			}
+		}
+	}
+	walk(p.root)
+
+	// Keep directories that were not loaded since they were restored
+	for path := range p.restoreOpen {
+		if _, found := p.lookup(path); !found && isBelow(path, p.root.path) {
+			dirs = append(dirs, p.relPath(path))
+		}
+	}
+
+	sort.Strings(dirs)
+
+	path := p.dataPath(expandedFile)
+
+	expanded := map[string][]string{}
+	readJSON(path, &expanded)
+
+	if len(dirs) > 0 {
+		expanded[p.root.path] = dirs
+	} else {
+		delete(expanded, p.root.path)
+	}
+
+	if err := writeJSON(path, expanded); err != nil {
+		log.Printf("save open dirs - err: %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/josa42/go-neovim/view"
+	"github.com/josa42/nvim-filetree/pkg/opener"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+func (p *FileProvider) create(i *FileItem) {
+	dir := filepath.Dir(i.path)
+	if i.isDir {
+		dir = i.path
+	}
+
+	name, ok := prompt.Input(p.api, "New file (end with / for a directory): ", "")
+	if !ok {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	if exists(path) {
+		p.api.Out.Print(fmt.Sprintf("%s already exists", p.relPath(path)))
+		return
+	}
+
+	mkdir := strings.HasSuffix(name, "/")
+	if err := createPath(path, mkdir); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not create %s: %v", p.relPath(path), err))
+		return
+	}
+
+	p.journal.record(operation{kind: operationCreate, path: path, isDir: mkdir})
+
+	p.reveal(path)
+}
+
+func (p *FileProvider) rename(items []*FileItem) {
+	if len(items) == 1 {
+		p.renameItem(items[0])
+		return
+	}
+
+	target, ok := prompt.Input(p.api, fmt.Sprintf("Move %d items to: ", len(items)), p.relPath(filepath.Dir(items[0].path))+"/")
+	if !ok {
+		return
+	}
+
+	dir := p.absPath(target)
+	last := ""
+
+	for _, i := range topLevelItems(items) {
+		path := filepath.Join(dir, i.name)
+		if path == i.path || !exists(i.path) {
+			continue
+		}
+
+		if exists(path) {
+			p.api.Out.Print(fmt.Sprintf("%s already exists", p.relPath(path)))
+			continue
+		}
+
+		if p.move(i, path) {
+			last = path
+		}
+	}
+
+	p.clearMarks()
+
+	if last != "" {
+		p.reveal(last)
+	}
+}
+
+func (p *FileProvider) renameItem(i *FileItem) {
+	if i == p.root {
+		return
+	}
+
+	target, ok := prompt.Input(p.api, "Move to: ", p.relPath(i.path))
+	if !ok {
+		return
+	}
+
+	path := p.absPath(target)
+	if path == i.path {
+		return
+	}
+
+	if exists(path) {
+		p.api.Out.Print(fmt.Sprintf("%s already exists", p.relPath(path)))
+		return
+	}
+
+	if p.move(i, path) {
+		p.clearMarks()
+		p.reveal(path)
+	}
+}
+
+func (p *FileProvider) move(i *FileItem, path string) bool {
+	if err := movePath(i.path, path); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not move %s: %v", p.relPath(i.path), err))
+		return false
+	}
+
+	p.journal.record(operation{kind: operationMove, path: i.path, target: path})
+
+	opener.RenameBuffers(p.api, i.path, path)
+	p.relocate(i, path)
+
+	return true
+}
+
+// relocate moves i into the item of its new parent directory, so the open
+// state of its subtree is kept.
+func (p *FileProvider) relocate(i *FileItem, path string) {
+	parent, found := p.find(filepath.Dir(path))
+
+	i.setPath(path)
+
+	if found {
+		children := []view.TreeItem{i}
+		for _, c := range parent.children {
+			if child, ok := c.(*FileItem); ok && child.name != i.name {
+				children = append(children, child)
+			}
+		}
+		parent.children = children
+	}
+}
+
+func (p *FileProvider) delete(items []*FileItem) {
+	message := fmt.Sprintf("Move %d items to the trash?", len(items))
+	if len(items) == 1 {
+		message = fmt.Sprintf("Move %s to the trash?", p.relPath(items[0].path))
+	}
+
+	if !prompt.Confirm(p.api, message) {
+		return
+	}
+
+	for _, i := range items {
+		// Skip the root and items already trashed along with their parent
+		if i == p.root || !exists(i.path) {
+			continue
+		}
+
+		trashPath, err := trash(i.path)
+		if err != nil {
+			p.api.Out.Print(fmt.Sprintf("Could not trash %s: %v", p.relPath(i.path), err))
+			continue
+		}
+
+		p.journal.record(operation{kind: operationTrash, path: i.path, target: trashPath})
+
+		opener.WipeBuffers(p.api, i.path)
+	}
+
+	p.clearMarks()
+}
+
+func (p *FileProvider) restoreDeleted(i *FileItem) {
+	if !i.isGhost {
+		p.api.Out.Print(fmt.Sprintf("%s is not deleted", p.relPath(i.path)))
+		return
+	}
+
+	if err := restore(p.root.path, i.path); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not restore %s: %v", p.relPath(i.path), err))
+		return
+	}
+
+	p.updateFileStatus()
+	p.reveal(i.path)
+}
+
+func (p *FileProvider) stage(i *FileItem) {
+	if err := stage(p.root.path, i.path); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not stage %s: %v", p.relPath(i.path), err))
+	}
+
+	p.updateFileStatus()
+}
+
+func (p *FileProvider) unstage(i *FileItem) {
+	if err := unstage(p.root.path, i.path); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not unstage %s: %v", p.relPath(i.path), err))
+	}
+
+	p.updateFileStatus()
+}
+
+func (p *FileProvider) discard(i *FileItem) {
+	if !prompt.Confirm(p.api, fmt.Sprintf("Discard changes to %s?", p.relPath(i.path))) {
+		return
+	}
+
+	if err := discard(p.root.path, i.path); err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not discard %s: %v", p.relPath(i.path), err))
+	}
+
+	p.updateFileStatus()
+}
+
+func (p *FileProvider) diff(i *FileItem) {
+	args := []string{"git", "-C", p.root.path, "diff"}
+
+	switch status := p.fileStatus.get(i.path, i.isDir); {
+	case status == FileStatusNormal, status == FileStatusIgnored:
+		p.api.Out.Print(fmt.Sprintf("%s has no changes", p.relPath(i.path)))
+		return
+	case status == FileStatusUntracked:
+		args = append(args, "--no-index", "--", os.DevNull, i.path)
+	case status == FileStatusConflicted:
+		args = append(args, "--", i.path)
+	case i.isDir && status == FileStatusStaged:
+		args = append(args, "--cached", "--", i.path)
+	case !i.isDir && p.fileStatus.code(i.path)[workTreeColumn] == ' ':
+		// Only the index differs, e.g. for staged deletions
+		args = append(args, "--cached", "--", i.path)
+	default:
+		args = append(args, "--", i.path)
+	}
+
+	opener.OpenCommandOutput(p.api, args, "diff")
+}
+
+func (p *FileProvider) diffSplit(i *FileItem) {
+	switch p.fileStatus.get(i.path, i.isDir) {
+	case FileStatusNormal, FileStatusUntracked, FileStatusIgnored:
+		p.api.Out.Print(fmt.Sprintf("%s has no changes", p.relPath(i.path)))
+		return
+	}
+
+	if i.isDir {
+		return
+	}
+
+	rev := "HEAD:./" + filepath.ToSlash(p.relPath(i.path))
+	if !revExists(p.root.path, rev) {
+		p.api.Out.Print(fmt.Sprintf("%s does not exist in HEAD", p.relPath(i.path)))
+		return
+	}
+
+	opener.DiffCommandOutput(p.api, i.path, []string{"git", "-C", p.root.path, "show", rev})
+}
+
+func createPath(path string, dir bool) error {
+	if dir {
+		return os.MkdirAll(path, 0755)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func movePath(path, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(path, target)
+}
@@ -0,0 +1,289 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/josa42/nvim-filetree/pkg/opener"
+)
+
+const (
+	maxFilterEntries   = 100000
+	filterPollInterval = 20 * time.Millisecond
+)
+
+// filterState narrows the tree to the files matching a query.
+type filterState struct {
+	query string
+	// matches maps matching files to the matched rune positions in their name
+	matches map[string][]int
+	// dirs contains all ancestors of matching files
+	dirs map[string]bool
+	top  string
+}
+
+func (f *filterState) contains(path string) bool {
+	if f == nil {
+		return true
+	}
+
+	_, ok := f.matches[path]
+	return ok || f.dirs[path]
+}
+
+func (f *filterState) expands(path string) bool {
+	return f != nil && f.dirs[path]
+}
+
+// runFilter reads keys until the filter is confirmed with enter, which opens
+// the top match, or cancelled with escape, which restores the previous tree.
+func (p *FileProvider) runFilter() {
+	done := make(chan struct{})
+	defer close(done)
+
+	results := make(chan []string, 1)
+	go func() {
+		results <- p.walkFiles(done)
+	}()
+
+	var files []string
+	loaded := false
+	query := ""
+
+	p.api.Out.Print("/")
+
+	for {
+		if !loaded {
+			select {
+			case files = <-results:
+				loaded = true
+				p.applyFilter(query, files)
+			default:
+			}
+		}
+
+		// Keys are polled while the files are still loaded, so escape cancels
+		// the walk in big trees
+		key, ok := p.readKey(loaded)
+		if !ok {
+			time.Sleep(filterPollInterval)
+			continue
+		}
+
+		switch key {
+		case "\x1b":
+			p.endFilter("")
+			return
+
+		case "\r":
+			top := ""
+			if p.filter != nil {
+				top = p.filter.top
+			}
+			p.endFilter(top)
+			return
+
+		case "\x80kb", "\b":
+			if r := []rune(query); len(r) > 0 {
+				query = string(r[:len(r)-1])
+			}
+
+		case "\x15":
+			query = ""
+
+		default:
+			if r := []rune(key); len(r) == 1 && unicode.IsPrint(r[0]) {
+				query += key
+			}
+		}
+
+		p.api.Out.Print("/" + query)
+
+		if loaded {
+			p.applyFilter(query, files)
+		}
+	}
+}
+
+func (p *FileProvider) endFilter(open string) {
+	p.filter = nil
+	p.api.Execute("call clearmatches(bufwinid(g:tree_buffer_id))")
+	p.api.Out.Print("")
+
+	if open != "" {
+		p.reveal(open)
+		opener.Activate(p.api, open)
+	}
+}
+
+// readKey returns the next typed key. Unless block is set, it returns false
+// if no key is available.
+func (p *FileProvider) readKey(block bool) (string, bool) {
+	var key interface{}
+	if block {
+		p.api.Eval("getchar()", &key)
+	} else {
+		p.api.Eval("getchar(0)", &key)
+	}
+
+	var code int64
+	switch k := key.(type) {
+	case string:
+		return k, k != ""
+	case int64:
+		code = k
+	case uint64:
+		code = int64(k)
+	case int:
+		code = int64(k)
+	}
+
+	if code == 0 {
+		return "", false
+	}
+
+	return string(rune(code)), true
+}
+
+func (p *FileProvider) applyFilter(query string, files []string) {
+	if query == "" {
+		p.filter = nil
+	} else {
+		f := &filterState{
+			query:   query,
+			matches: map[string][]int{},
+			dirs:    map[string]bool{},
+		}
+
+		best := 0
+		for _, path := range files {
+			rel := p.relPath(path)
+
+			positions, score, ok := fuzzyMatch(query, rel)
+			if !ok {
+				continue
+			}
+
+			nameStart := len([]rune(rel)) - len([]rune(filepath.Base(rel)))
+			inName := []int{}
+			for _, pos := range positions {
+				if pos >= nameStart {
+					inName = append(inName, pos-nameStart)
+				}
+			}
+			f.matches[path] = inName
+
+			for dir := filepath.Dir(path); isBelow(dir, p.root.path); dir = filepath.Dir(dir) {
+				f.dirs[dir] = true
+			}
+
+			if f.top == "" || score > best || score == best && len(path) < len(f.top) {
+				best = score
+				f.top = path
+			}
+		}
+
+		p.filter = f
+	}
+
+	p.triggerChange()
+	p.highlightFilter()
+	p.api.Execute("redraw")
+}
+
+// highlightFilter highlights the matched characters of all visible matches
+// and moves the cursor onto the top match.
+func (p *FileProvider) highlightFilter() {
+	p.api.Execute("call clearmatches(bufwinid(g:tree_buffer_id))")
+
+	if p.filter == nil {
+		return
+	}
+
+	p.updateVisibleItems()
+
+	for idx, item := range p.visibleItems {
+		line := idx + 1
+
+		if item.shows(p.filter.top) {
+			p.setCursor(line)
+		}
+
+		positions := p.filter.matches[item.path]
+		if len(positions) == 0 {
+			continue
+		}
+
+		name := []rune(item.name)
+		prefix := p.linePrefixLen(item)
+
+		pos := []string{}
+		for _, n := range positions {
+			col := prefix + len(string(name[:n])) + 1
+			pos = append(pos, fmt.Sprintf("[%d, %d, %d]", line, col, len(string(name[n]))))
+		}
+
+		// matchaddpos() accepts up to 8 positions at once
+		for start := 0; start < len(pos); start += 8 {
+			end := start + 8
+			if end > len(pos) {
+				end = len(pos)
+			}
+
+			p.api.Executef("call matchaddpos('TreeFilterMatch', [%s], 10, -1, {'window': bufwinid(g:tree_buffer_id)})", strings.Join(pos[start:end], ", "))
+		}
+	}
+}
+
+// linePrefixLen returns the length in bytes of the rendered line of item
+// before its name: indentation, status, icon, separating spaces and the
+// directories of a compacted chain.
+func (p *FileProvider) linePrefixLen(item *FileItem) int {
+	chain := len(item.displayName()) - len(item.name)
+
+	return item.depth*2 + len(string(item.Status())) + 1 + len(string(item.icon())) + 1 + chain
+}
+
+// walkFiles returns all files below the root that are shown with the active
+// filters. It stops early once done is closed.
+func (p *FileProvider) walkFiles(done <-chan struct{}) []string {
+	files := []string{}
+	dirs := []string{p.root.path}
+	count := 0
+
+	for len(dirs) > 0 {
+		select {
+		case <-done:
+			return files
+		default:
+		}
+
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+
+			if _, visible := p.visibility(path, e.IsDir(), false); !visible {
+				continue
+			}
+
+			if count++; count > maxFilterEntries {
+				return files
+			}
+
+			if e.IsDir() {
+				dirs = append(dirs, path)
+			} else {
+				files = append(files, path)
+			}
+		}
+	}
+
+	return files
+}
@@ -0,0 +1,61 @@
+package files
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyMatch reports whether the runes of query appear in text in the same
+// order. It returns the positions of the matched runes in text and a score,
+// where higher is better. Matching starts at the end of text, so matches in
+// the file name are preferred over matches in its directories. The match is
+// case sensitive only if query contains upper case letters.
+func fuzzyMatch(query, text string) ([]int, int, bool) {
+	q := []rune(query)
+	t := []rune(text)
+
+	caseSensitive := strings.ToLower(query) != query
+
+	positions := make([]int, len(q))
+	qi := len(q) - 1
+
+	for ti := len(t) - 1; ti >= 0 && qi >= 0; ti-- {
+		if equalRunes(q[qi], t[ti], caseSensitive) {
+			positions[qi] = ti
+			qi--
+		}
+	}
+
+	if qi >= 0 {
+		return nil, 0, false
+	}
+
+	nameStart := strings.LastIndex(text, "/") + 1
+	nameStart = len([]rune(text[:nameStart]))
+
+	score := 0
+	for idx, pos := range positions {
+		score++
+
+		if idx > 0 && positions[idx-1] == pos-1 {
+			score += 5
+		}
+
+		if pos == 0 || strings.ContainsRune("/._- ", t[pos-1]) {
+			score += 3
+		}
+
+		if pos >= nameStart {
+			score += 2
+		}
+	}
+
+	return positions, score, true
+}
+
+func equalRunes(a, b rune, caseSensitive bool) bool {
+	if caseSensitive {
+		return a == b
+	}
+	return unicode.ToLower(a) == unicode.ToLower(b)
+}
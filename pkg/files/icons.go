@@ -0,0 +1,382 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/josa42/go-neovim/view"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+// GlobalVarIconTheme selects the icon theme by name. Without it the
+// "nerdfont" theme is used if g:nerdfont is set, otherwise "default".
+//
+// GlobalVarIconThemes names a dictionary of additional themes, based on the
+// "default" theme. A theme sets the icons for "closed" and "open" directories
+// and for "file"s, the "mark" of marked items, the "title" and the git status
+// glyphs "changed", "added", "conflicted", "staged", "partial", "deleted",
+// "deleted_staged" and "ignored". "filetypes" enables the per-filetype icons:
+//
+//	let g:tree_icon_themes = {'arrows': {'closed': '→', 'open': '↓', 'file': '·'}}
+//	let g:tree_icon_theme = 'arrows'
+const (
+	GlobalVarIconTheme  = "tree_icon_theme"
+	GlobalVarIconThemes = "tree_icon_themes"
+)
+
+type iconTheme struct {
+	name      string
+	title     string
+	closed    rune
+	open      rune
+	file      rune
+	mark      rune
+	fileIcons bool
+	status    statusGlyphs
+}
+
+type statusGlyphs struct {
+	changed       rune
+	added         rune
+	conflicted    rune
+	staged        rune
+	partial       rune
+	deleted       rune
+	deletedStaged rune
+	ignored       rune
+}
+
+var unicodeStatus = statusGlyphs{
+	changed:       view.ItemStatusChanged,
+	added:         view.ItemStatusAdded,
+	conflicted:    view.ItemStatusConflicted,
+	staged:        '●',
+	partial:       '◐',
+	deleted:       '⊘',
+	deletedStaged: '⊖',
+	ignored:       '◌',
+}
+
+var iconThemes = map[string]iconTheme{
+	"nerdfont": {title: "פּ", closed: '', open: 'ﱮ', file: '', mark: '✓', fileIcons: true, status: unicodeStatus},
+	"default":  {title: "פּ", closed: '▸', open: '▾', file: '•', mark: '✓', status: unicodeStatus},
+	"ascii": {title: "tree", closed: '+', open: '-', file: '*', mark: '*', status: statusGlyphs{
+		changed:       '~',
+		added:         '?',
+		conflicted:    '!',
+		staged:        '=',
+		partial:       ':',
+		deleted:       'x',
+		deletedStaged: 'X',
+		ignored:       '.',
+	}},
+}
+
+func (p *FileProvider) loadIconTheme() iconTheme {
+	themes := map[string]iconTheme{}
+	for name, theme := range iconThemes {
+		themes[name] = theme
+	}
+
+	custom := map[string]map[string]interface{}{}
+	p.api.Eval(fmt.Sprintf("get(g:, %s, {})", prompt.Quote(GlobalVarIconThemes)), &custom)
+
+	for name, values := range custom {
+		theme := themes["default"]
+
+		glyphs := map[string]*rune{
+			"closed":         &theme.closed,
+			"open":           &theme.open,
+			"file":           &theme.file,
+			"mark":           &theme.mark,
+			"changed":        &theme.status.changed,
+			"added":          &theme.status.added,
+			"conflicted":     &theme.status.conflicted,
+			"staged":         &theme.status.staged,
+			"partial":        &theme.status.partial,
+			"deleted":        &theme.status.deleted,
+			"deleted_staged": &theme.status.deletedStaged,
+			"ignored":        &theme.status.ignored,
+		}
+
+		for key, value := range values {
+			if glyph, ok := glyphs[key]; ok {
+				*glyph = runeValue(value, *glyph)
+				continue
+			}
+
+			switch key {
+			case "title":
+				if title, ok := value.(string); ok && strings.TrimSpace(title) != "" {
+					theme.title = title
+				}
+			case "filetypes":
+				theme.fileIcons = boolValue(value)
+			}
+		}
+
+		themes[name] = theme
+	}
+
+	name := ""
+	p.api.Eval(fmt.Sprintf("get(g:, %s, '')", prompt.Quote(GlobalVarIconTheme)), &name)
+	if name == "" {
+		name = "default"
+		if p.api.Global.Vars.Bool("nerdfont") {
+			name = "nerdfont"
+		}
+	}
+
+	theme, ok := themes[name]
+	if !ok {
+		// The theme is reloaded on every update, report it only once
+		if p.iconTheme.name != name {
+			p.api.Out.Print(fmt.Sprintf("Unknown icon theme: %s", name))
+		}
+		theme = themes["default"]
+	}
+
+	theme.name = name
+	return theme
+}
+
+// GlobalVarIcons names a dictionary that extends or overrides the file icons.
+// Keys are file names or extensions with a leading dot, values are either the
+// icon or a dictionary with "icon" and an optional "highlight" group:
+//
+//	let g:tree_icons = {'.zig': '', 'BUILD': {'icon': '', 'highlight': 'Special'}}
+const GlobalVarIcons = "tree_icons"
+
+type fileIcon struct {
+	glyph     rune
+	highlight string
+	color     string
+}
+
+// defaultFileIcons maps file names and extensions to nerd font icons.
+var defaultFileIcons = map[string]fileIcon{
+	".go":          {'', "TreeIconGo", "#519aba"},
+	"go.mod":       {'', "TreeIconGo", "#519aba"},
+	"go.sum":       {'', "TreeIconGo", "#519aba"},
+	".md":          {'', "TreeIconMarkdown", "#519aba"},
+	"Dockerfile":   {'', "TreeIconDocker", "#458ee6"},
+	"Makefile":     {'', "TreeIconMakefile", "#6d8086"},
+	"package.json": {'', "TreeIconNpm", "#e8274b"},
+	".js":          {'', "TreeIconJavaScript", "#cbcb41"},
+	".ts":          {'', "TreeIconTypeScript", "#519aba"},
+	".json":        {'', "TreeIconJson", "#cbcb41"},
+	".vim":         {'', "TreeIconVim", "#019833"},
+	".lua":         {'', "TreeIconLua", "#51a0cf"},
+	".py":          {'', "TreeIconPython", "#ffbc03"},
+	".rs":          {'', "TreeIconRust", "#dea584"},
+	".sh":          {'', "TreeIconShell", "#89e051"},
+	".yml":         {'', "TreeIconYaml", "#6d8086"},
+	".yaml":        {'', "TreeIconYaml", "#6d8086"},
+	".html":        {'', "TreeIconHtml", "#e34c26"},
+	".css":         {'', "TreeIconCss", "#42a5f5"},
+	".lock":        {'', "TreeIconLock", "#bbbbbb"},
+	"LICENSE":      {'', "TreeIconLicense", "#d0bf41"},
+	".gitignore":   {'', "TreeIconGit", "#f54d27"},
+}
+
+func (p *FileProvider) loadFileIcons() map[string]fileIcon {
+	icons := map[string]fileIcon{}
+	for key, icon := range defaultFileIcons {
+		icons[key] = icon
+	}
+
+	custom := map[string]interface{}{}
+	p.api.Eval(fmt.Sprintf("get(g:, %s, {})", prompt.Quote(GlobalVarIcons)), &custom)
+
+	for key, value := range custom {
+		icon := fileIcon{highlight: "TreeFileIcon"}
+
+		switch v := value.(type) {
+		case string:
+			icon.glyph = runeValue(v, 0)
+
+		case map[string]interface{}:
+			icon.glyph = runeValue(v["icon"], 0)
+			if highlight, ok := v["highlight"].(string); ok && highlight != "" {
+				icon.highlight = highlight
+			}
+		}
+
+		if icon.glyph != 0 {
+			icons[key] = icon
+		}
+	}
+
+	return icons
+}
+
+func (p *FileProvider) fileIcon(name string) (fileIcon, bool) {
+	if icon, ok := p.fileIcons[name]; ok {
+		return icon, true
+	}
+
+	icon, ok := p.fileIcons[strings.ToLower(filepath.Ext(name))]
+	return icon, ok
+}
+
+// BufferVarSyntax holds the syntax rules that depend on the icon theme,
+// syntax/tree.vim applies them whenever the syntax is loaded.
+const BufferVarSyntax = "tree_syntax"
+
+// ApplySyntax stores the syntax rules of the active theme in the tree buffer
+// and reloads its syntax.
+func (p *FileProvider) ApplySyntax(buffer int) {
+	p.syntax = p.syntaxRules()
+
+	rules := []string{}
+	for _, rule := range p.syntax {
+		rules = append(rules, prompt.Quote(rule))
+	}
+
+	p.api.Executef("call setbufvar(%d, %s, [%s])", buffer, prompt.Quote(BufferVarSyntax), strings.Join(rules, ", "))
+	p.api.Executef("lua vim.api.nvim_buf_call(%d, function() vim.bo.syntax = 'tree' end)", buffer)
+}
+
+// updateIconTheme reloads the icon theme, so that changed options apply to
+// the open tree.
+func (p *FileProvider) updateIconTheme() {
+	p.iconTheme = p.loadIconTheme()
+	p.fileIcons = p.loadFileIcons()
+
+	if p.syntax == nil || equalStrings(p.syntaxRules(), p.syntax) {
+		return
+	}
+
+	if buffer := p.api.Global.Vars.Int("tree_buffer_id"); buffer > 0 {
+		p.ApplySyntax(buffer)
+	}
+}
+
+// syntaxRules returns the commands to highlight the icons, the status glyphs
+// and the mark of the active theme.
+func (p *FileProvider) syntaxRules() []string {
+	theme := p.iconTheme
+	dirIcons := syntaxCollection([]rune{theme.closed, theme.open})
+	fileIcons := []rune{theme.file}
+
+	groups := map[string][]rune{}
+	colors := map[string]string{}
+
+	if theme.fileIcons {
+		// Sorted, to compare the rules with the ones that are applied
+		keys := []string{}
+		for key := range p.fileIcons {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			icon := p.fileIcons[key]
+			groups[icon.highlight] = append(groups[icon.highlight], icon.glyph)
+			fileIcons = append(fileIcons, icon.glyph)
+			if icon.color != "" {
+				colors[icon.highlight] = icon.color
+			}
+		}
+	}
+
+	cmds := []string{
+		fmt.Sprintf("syntax match TreeDirIcon /%s/ containedin=TreeIcon", dirIcons),
+		fmt.Sprintf("syntax match TreeFileIcon /%s/ containedin=TreeIcon", syntaxCollection(fileIcons)),
+		fmt.Sprintf("syntax match TreeDirName /\\(^\\(  \\)*. %s \\)\\@<=.*$/", dirIcons),
+		fmt.Sprintf("syntax match TreeFileName /\\(^\\(  \\)*. %s \\)\\@<=.*$/", syntaxCollection(fileIcons)),
+		fmt.Sprintf("syntax match TreeMarked / %s\\( \\{2,}\\|$\\)\\@=/ containedin=TreeName,TreeDirName,TreeFileName", syntaxCollection([]rune{theme.mark})),
+	}
+
+	for _, status := range []struct {
+		group string
+		glyph rune
+	}{
+		{"TreeStatusChanged", theme.status.changed},
+		{"TreeStatusAdded", theme.status.added},
+		{"TreeStatusConcflicted", theme.status.conflicted},
+		{"TreeStatusStaged", theme.status.staged},
+		{"TreeStatusPartial", theme.status.partial},
+	} {
+		cmds = append(cmds, fmt.Sprintf("syntax match %s /\\(^\\(  \\)*\\)\\@<=%s/ containedin=TreeStatus", status.group, syntaxCollection([]rune{status.glyph})))
+	}
+
+	cmds = append(cmds,
+		fmt.Sprintf("syntax match TreeGhost /\\(^\\(  \\)*\\)\\@<=%s.*$/", syntaxCollection([]rune{theme.status.deleted, theme.status.deletedStaged})),
+		fmt.Sprintf("syntax match TreeIgnored /\\(^\\(  \\)*\\)\\@<=%s.*$/", syntaxCollection([]rune{theme.status.ignored})),
+	)
+
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cmds = append(cmds, fmt.Sprintf("syntax match %s /%s/ containedin=TreeIcon", name, syntaxCollection(groups[name])))
+
+		if color, ok := colors[name]; ok {
+			cmds = append(cmds, fmt.Sprintf("highlight default %s guifg=%s", name, color))
+		}
+	}
+
+	return cmds
+}
+
+// syntaxCollection returns a pattern matching any of the glyphs. The glyphs
+// are written as code points so that they never need escaping.
+func syntaxCollection(glyphs []rune) string {
+	b := strings.Builder{}
+	b.WriteString("[")
+	for _, glyph := range glyphs {
+		if glyph > 0xffff {
+			fmt.Fprintf(&b, "\\U%08x", glyph)
+		} else {
+			fmt.Fprintf(&b, "\\u%04x", glyph)
+		}
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
+
+// runeValue returns the first character of a string value or def. Whitespace
+// is rejected, the syntax rules rely on every glyph being visible.
+func runeValue(value interface{}, def rune) rune {
+	if s, ok := value.(string); ok {
+		for _, r := range s {
+			if unicode.IsSpace(r) {
+				break
+			}
+			return r
+		}
+	}
+	return def
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func boolValue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	return false
+}
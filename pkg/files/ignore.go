@@ -0,0 +1,307 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignorePattern is a single line of a gitignore file.
+type ignorePattern struct {
+	exp     *regexp.Regexp
+	base    string
+	negate  bool
+	dirOnly bool
+}
+
+func (pattern ignorePattern) match(path string, isDir bool) bool {
+	if pattern.dirOnly && !isDir {
+		return false
+	}
+
+	rel, err := filepath.Rel(pattern.base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	return pattern.exp.MatchString(filepath.ToSlash(rel))
+}
+
+// ignoreMatcher matches paths against the gitignore files of a work tree:
+// core.excludesFile, info/exclude in the git directory and the .gitignore
+// file of every directory, in ascending order of precedence.
+type ignoreMatcher struct {
+	dir      string
+	workTree string
+	global   []string
+
+	mu       sync.Mutex
+	patterns map[string][]ignorePattern
+	dirs     map[string][]ignorePattern
+}
+
+func newIgnoreMatcher(dir string) *ignoreMatcher {
+	m := &ignoreMatcher{
+		dir:      dir,
+		workTree: dir,
+		patterns: map[string][]ignorePattern{},
+		dirs:     map[string][]ignorePattern{},
+	}
+
+	if workTree, ok := findWorkTree(dir); ok {
+		m.workTree = workTree
+		m.global = []string{excludesFile(dir)}
+		if exclude, ok := gitPath(dir, "info/exclude"); ok {
+			m.global = append(m.global, exclude)
+		}
+	}
+
+	return m
+}
+
+// files returns the ignore files that apply to the whole work tree.
+func (m *ignoreMatcher) files() []string {
+	return m.global
+}
+
+// reset drops all loaded patterns, so changed ignore files are read again.
+func (m *ignoreMatcher) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.patterns = map[string][]ignorePattern{}
+	m.dirs = map[string][]ignorePattern{}
+}
+
+// match reports whether path is ignored, either directly or because one of
+// its parent directories is ignored.
+func (m *ignoreMatcher) match(path string, isDir bool) bool {
+	rel, err := filepath.Rel(m.workTree, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	current := m.workTree
+	parts := strings.Split(rel, string(filepath.Separator))
+
+	for idx, name := range parts {
+		current = filepath.Join(current, name)
+		if m.matchPath(current, isDir || idx < len(parts)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *ignoreMatcher) matchPath(path string, isDir bool) bool {
+	ignored := false
+
+	for _, pattern := range m.patternsFor(filepath.Dir(path)) {
+		if pattern.match(path, isDir) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+// patternsFor returns all patterns that apply to the entries of dir.
+func (m *ignoreMatcher) patternsFor(dir string) []ignorePattern {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.collect(dir)
+}
+
+// collect returns the patterns of dir, which extend those of its parent. They
+// are cached per directory. The caller holds mu.
+func (m *ignoreMatcher) collect(dir string) []ignorePattern {
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns
+	}
+
+	patterns := []ignorePattern{}
+
+	rel, err := filepath.Rel(m.workTree, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		for _, file := range m.global {
+			patterns = append(patterns, m.load(file, m.workTree)...)
+		}
+		if err == nil && rel == "." {
+			patterns = append(patterns, m.load(filepath.Join(dir, ".gitignore"), dir)...)
+		}
+	} else {
+		patterns = append(patterns, m.collect(filepath.Dir(dir))...)
+		patterns = append(patterns, m.load(filepath.Join(dir, ".gitignore"), dir)...)
+	}
+
+	m.dirs[dir] = patterns
+
+	return patterns
+}
+
+func (m *ignoreMatcher) load(file, base string) []ignorePattern {
+	if patterns, ok := m.patterns[file]; ok {
+		return patterns
+	}
+
+	patterns := readIgnoreFile(file, base)
+	m.patterns[file] = patterns
+
+	return patterns
+}
+
+func readIgnoreFile(file, base string) []ignorePattern {
+	patterns := []ignorePattern{}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return patterns
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if pattern, ok := parseIgnorePattern(scanner.Text(), base); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+func parseIgnorePattern(line, base string) (ignorePattern, bool) {
+	pattern := ignorePattern{base: base}
+
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless they are escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if line == "" {
+		return pattern, false
+	}
+
+	// Patterns with a slash are relative to the directory of the ignore file,
+	// others match a name at any level below it
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	exp := globToRegexp(line)
+	if !anchored && !strings.HasPrefix(exp, "(.*/)?") {
+		exp = "(.*/)?" + exp
+	}
+
+	compiled, err := regexp.Compile("^" + exp + "$")
+	if err != nil {
+		return pattern, false
+	}
+	pattern.exp = compiled
+
+	return pattern, true
+}
+
+// globToRegexp converts a gitignore glob into a regular expression.
+func globToRegexp(glob string) string {
+	exp := strings.Builder{}
+
+	for idx := 0; idx < len(glob); idx++ {
+		c := glob[idx]
+
+		switch {
+		case strings.HasPrefix(glob[idx:], "**/") && (idx == 0 || glob[idx-1] == '/'):
+			exp.WriteString("(.*/)?")
+			idx += 2
+
+		case glob[idx:] == "**" && idx > 0 && glob[idx-1] == '/':
+			exp.WriteString(".*")
+			idx++
+
+		case c == '*':
+			exp.WriteString("[^/]*")
+
+		case c == '?':
+			exp.WriteString("[^/]")
+
+		case c == '[':
+			end := strings.IndexByte(glob[idx+1:], ']')
+			if end < 0 {
+				exp.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[idx+1 : idx+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			exp.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			idx += end + 1
+
+		case c == '\\' && idx+1 < len(glob):
+			idx++
+			exp.WriteString(regexp.QuoteMeta(string(glob[idx])))
+
+		default:
+			exp.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return exp.String()
+}
+
+// findWorkTree returns the closest parent of dir that contains a .git entry.
+func findWorkTree(dir string) (string, bool) {
+	for {
+		if exists(filepath.Join(dir, ".git")) {
+			return dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// excludesFile returns the path of core.excludesFile, defaulting to
+// $XDG_CONFIG_HOME/git/ignore.
+func excludesFile(dir string) string {
+	cmd := git("config", "--path", "core.excludesFile")
+	cmd.Dir = dir
+
+	if out, err := cmd.Output(); err == nil {
+		if path := strings.TrimSpace(string(out)); path != "" {
+			return path
+		}
+	}
+
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "git", "ignore")
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "git", "ignore")
+}
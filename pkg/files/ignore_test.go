@@ -0,0 +1,158 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"*.log", `[^/]*\.log`},
+		{"a?c", `a[^/]c`},
+		{"[a-c].txt", `[a-c]\.txt`},
+		{"[!a]b", `[^a]b`},
+		{"[abc", `\[abc`},
+		{"**/cache", `(.*/)?cache`},
+		{"logs/**", `logs/.*`},
+		{"docs/**/*.tmp", `docs/(.*/)?[^/]*\.tmp`},
+		{"foo**bar", `foo[^/]*[^/]*bar`},
+		{`\#hash`, `#hash`},
+		{`\*star`, `\*star`},
+		{`space\ `, `space `},
+	}
+
+	for _, test := range tests {
+		if got := globToRegexp(test.glob); got != test.want {
+			t.Errorf("globToRegexp(%q) = %q, want %q", test.glob, got, test.want)
+		}
+	}
+}
+
+func TestParseIgnorePattern(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		negate  bool
+		dirOnly bool
+		exp     string
+	}{
+		{line: "", ok: false},
+		{line: "   ", ok: false},
+		{line: "# comment", ok: false},
+		{line: "/", ok: false},
+		{line: "*.log", ok: true, exp: `^(.*/)?[^/]*\.log$`},
+		{line: "*.log  ", ok: true, exp: `^(.*/)?[^/]*\.log$`},
+		{line: "*.log\r", ok: true, exp: `^(.*/)?[^/]*\.log$`},
+		{line: "!keep.log", ok: true, negate: true, exp: `^(.*/)?keep\.log$`},
+		{line: "build/", ok: true, dirOnly: true, exp: `^(.*/)?build$`},
+		{line: "/root.txt", ok: true, exp: `^root\.txt$`},
+		{line: "docs/*.md", ok: true, exp: `^docs/[^/]*\.md$`},
+		{line: "**/cache", ok: true, exp: `^(.*/)?cache$`},
+		{line: "logs/**", ok: true, exp: `^logs/.*$`},
+		{line: `\#hash`, ok: true, exp: `^(.*/)?#hash$`},
+		{line: `\!bang`, ok: true, exp: `^(.*/)?!bang$`},
+		{line: `space\ `, ok: true, exp: `^(.*/)?space $`},
+	}
+
+	for _, test := range tests {
+		pattern, ok := parseIgnorePattern(test.line, "/repo")
+		if ok != test.ok {
+			t.Errorf("parseIgnorePattern(%q) ok = %v, want %v", test.line, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if pattern.negate != test.negate || pattern.dirOnly != test.dirOnly {
+			t.Errorf("parseIgnorePattern(%q) negate, dirOnly = %v, %v, want %v, %v", test.line, pattern.negate, pattern.dirOnly, test.negate, test.dirOnly)
+		}
+		if got := pattern.exp.String(); got != test.exp {
+			t.Errorf("parseIgnorePattern(%q) exp = %q, want %q", test.line, got, test.exp)
+		}
+	}
+}
+
+func TestIgnoreMatcherMatch(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".gitignore": strings.Join([]string{
+			"*.log",
+			"!keep.log",
+			"/root-only.txt",
+			"build/",
+			"logs/**",
+			"**/cache",
+			"docs/**/*.tmp",
+			"excluded/",
+			"!excluded/file.txt",
+			`\#hash`,
+			`\!bang`,
+			`space\ `,
+			"trailing   ",
+		}, "\n"),
+		"sub/.gitignore": "!*.log\n",
+	}
+
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := newIgnoreMatcher(root)
+	// Do not depend on the global excludes of the machine running the tests
+	m.global = nil
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"dir/a.log", false, true},
+		{"keep.log", false, false},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"build", true, true},
+		{"build/x.txt", false, true},
+		{"other/build", false, false},
+		{"logs", true, false},
+		{"logs/a/b.txt", false, true},
+		{"cache", false, true},
+		{"a/b/cache", true, true},
+		{"docs/x.tmp", false, true},
+		{"docs/a/b/x.tmp", false, true},
+		{"x.tmp", false, false},
+		// A file can not be re-included if its parent directory is excluded
+		{"excluded/file.txt", false, true},
+		{"#hash", false, true},
+		{"!bang", false, true},
+		{"space ", false, true},
+		{"space", false, false},
+		{"trailing", false, true},
+		{"trailing ", false, false},
+		// The nested .gitignore re-includes the files excluded by the root
+		{"sub/x.log", false, false},
+		{"sub/deeper/y.log", false, false},
+	}
+
+	for _, test := range tests {
+		if got := m.match(filepath.Join(root, test.path), test.isDir); got != test.want {
+			t.Errorf("match(%q, %v) = %v, want %v", test.path, test.isDir, got, test.want)
+		}
+	}
+}
@@ -2,17 +2,13 @@ package files
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 
 	"github.com/josa42/go-neovim/view"
 )
 
-var iconThemes = map[string][]rune{
-	"nerdfont": {'', 'ﱮ', ''},
-	"default":  {'▸', '▾', '•'},
-}
-
 // Interface Assertions
 var _ view.TreeItem = (*FileItem)(nil)
 var _ view.Openable = (*FileItem)(nil)
@@ -23,6 +19,14 @@ type FileItem struct {
 	path        string
 	isDir       bool
 	isOpen      bool
+	isMarked    bool
+	isGhost     bool
+	isIgnored   bool
+	depth       int
+	chainHead   string
+	inChain     bool
+	entries     *entryCache
+	fileInfo    os.FileInfo
 	children    []view.TreeItem
 	matchIgnore *func(string) bool
 	provider    *FileProvider
@@ -41,36 +45,59 @@ func NewFileItem(parentPath, name string, provider *FileProvider) *FileItem {
 }
 
 func (i *FileItem) Children() []view.TreeItem {
+	children := i.list()
+
+	depth := 0
+	if i != i.provider.root {
+		depth = i.depth + 1
+	}
+
+	for idx, c := range children {
+		if child, ok := c.(*FileItem); ok {
+			child = child.compact()
+			child.depth = depth
+			children[idx] = child
+		}
+	}
+
+	return children
+}
+
+// list returns the visible children of the directory, without compacting
+// directory chains.
+func (i *FileItem) list() []view.TreeItem {
 	names := childrenNames(i.path)
 	children := []view.TreeItem{}
 
+	// Deleted files do not exist on disk anymore, they are merged in from the
+	// git status
+	ghosts := i.provider.fileStatus.deleted(i.path)
 	for _, name := range names {
+		delete(ghosts, name)
+	}
+	for name := range ghosts {
+		names = append(names, name)
+	}
 
-		found := false
+	for _, name := range names {
+		child := i.child(name)
+		child.fileInfo = nil
 
-		for _, c := range i.children {
-			child, _ := c.(*FileItem)
-			if child.name == name {
-				children = append(children, child)
-				found = true
-				continue
-			}
+		ghostIsDir, isGhost := ghosts[name]
+		child.isGhost = isGhost
+		if isGhost {
+			child.isDir = ghostIsDir
 		}
 
-		if !found {
-			children = append(children, NewFileItem(i.path, name, i.provider))
-		}
+		children = append(children, child)
 	}
 
+	order := i.provider.sortOrder
 	sort.Slice(children, func(i, j int) bool {
 		a, _ := children[i].(*FileItem)
 		b, _ := children[j].(*FileItem)
 
-		if a.isDir == b.isDir {
-			return a.name < b.name
-		}
-
-		return a.isDir
+		return order.less(a, b)
 	})
 
 	i.children = children
@@ -78,8 +105,15 @@ func (i *FileItem) Children() []view.TreeItem {
 	filtered := []view.TreeItem{}
 
 	for _, c := range children {
-		i, ok := c.(*FileItem)
-		if ok && !i.provider.isIgnored(i.path) && i.provider.fileStatus.get(i.path, false) != FileStatusIgnored {
+		child, ok := c.(*FileItem)
+		if !ok {
+			continue
+		}
+
+		ignored, visible := i.provider.visibility(child.path, child.isDir, child.isGhost)
+		child.isIgnored = ignored
+
+		if visible && i.provider.filter.contains(child.path) {
 			filtered = append(filtered, c)
 		}
 	}
@@ -87,17 +121,59 @@ func (i *FileItem) Children() []view.TreeItem {
 	return filtered
 }
 
+// child returns the loaded child item with name, or a new one.
+func (i *FileItem) child(name string) *FileItem {
+	for _, c := range i.children {
+		if child, _ := c.(*FileItem); child.name == name {
+			return child
+		}
+	}
+
+	child := NewFileItem(i.path, name, i.provider)
+	child.isOpen = child.isDir && i.provider.restoreOpen[child.path]
+
+	return child
+}
+
+// stat returns the file info of the item. It is read once for each listing of
+// the parent directory.
+func (i *FileItem) stat() os.FileInfo {
+	if i.fileInfo == nil && !i.isGhost {
+		i.fileInfo, _ = os.Lstat(i.path)
+	}
+	return i.fileInfo
+}
+
+func (i *FileItem) setPath(path string) {
+	i.path = path
+	i.name = filepath.Base(path)
+
+	for _, c := range i.children {
+		if child, ok := c.(*FileItem); ok {
+			child.setPath(filepath.Join(path, child.name))
+		}
+	}
+}
+
 func (i *FileItem) String() string {
 	icon := i.icon()
+	mark := ""
+	if i.isMarked {
+		mark = " " + string(i.provider.iconTheme.mark)
+	}
+
+	name := i.displayName()
+
+	text := fmt.Sprintf("%c %s%s", icon, name, mark)
 	if i.isDir {
-		if i.isOpen {
-			return fmt.Sprintf("%c %s/", icon, i.name)
-		} else {
-			return fmt.Sprintf("%c %s/", icon, i.name)
-		}
+		text = fmt.Sprintf("%c %s/%s", icon, name, mark)
+	}
+
+	if i.provider.showDetails {
+		return i.provider.withDetails(i, text)
 	}
 
-	return fmt.Sprintf("%c %s", icon, i.name)
+	return text
 }
 
 // Openable Interface
@@ -107,7 +183,7 @@ func (i *FileItem) IsOpenable() bool {
 }
 
 func (i *FileItem) IsOpen() bool {
-	return i.isOpen
+	return i.isOpen || i.inChain || i.provider.filter.expands(i.path)
 }
 
 func (i *FileItem) Open() {
@@ -119,34 +195,56 @@ func (i *FileItem) Close() {
 }
 
 func (i *FileItem) icon() rune {
-	icons := iconThemes["default"]
-	if i.provider.api.Global.Vars.Bool("nerdfont") {
-		icons = iconThemes["nerdfont"]
-	}
+	theme := i.provider.iconTheme
 	if i.isDir {
-		if !i.isOpen {
-			return icons[0]
+		if !i.IsOpen() {
+			return theme.closed
 
 		} else {
-			return icons[1]
+			return theme.open
 		}
 	}
 
-	return icons[2]
+	if theme.fileIcons {
+		if icon, ok := i.provider.fileIcon(i.name); ok {
+			return icon.glyph
+		}
+	}
+
+	return theme.file
 }
 
 // statusable interface
 
 func (i *FileItem) Status() rune {
+	glyphs := i.provider.iconTheme.status
+
+	if i.isGhost {
+		if i.provider.fileStatus.deletionStaged(i.path) {
+			return glyphs.deletedStaged
+		}
+		return glyphs.deleted
+	}
+
+	if i.isIgnored {
+		return glyphs.ignored
+	}
+
 	switch i.provider.fileStatus.get(i.path, i.isDir) {
 	case FileStatusChanged:
-		return view.ItemStatusChanged
+		return glyphs.changed
+
+	case FileStatusStaged:
+		return glyphs.staged
+
+	case FileStatusPartiallyStaged:
+		return glyphs.partial
 
 	case FileStatusUntracked:
-		return view.ItemStatusAdded
+		return glyphs.added
 
 	case FileStatusConflicted:
-		return view.ItemStatusConflicted
+		return glyphs.conflicted
 
 	default:
 		return ' '
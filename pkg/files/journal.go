@@ -0,0 +1,199 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/josa42/nvim-filetree/pkg/opener"
+)
+
+type operationKind int
+
+const (
+	operationCreate operationKind = iota
+	operationMove
+	operationCopy
+	operationTrash
+)
+
+// operation is a file system change done from the tree. For moves and copies
+// target is the new path, for trashed files it is the location in the trash.
+type operation struct {
+	kind   operationKind
+	path   string
+	target string
+	isDir  bool
+}
+
+type journal struct {
+	done   []operation
+	undone []operation
+}
+
+func (j *journal) record(op operation) {
+	j.done = append(j.done, op)
+	j.undone = nil
+}
+
+func (p *FileProvider) undo() {
+	if len(p.journal.done) == 0 {
+		p.api.Out.Print("Nothing to undo")
+		return
+	}
+
+	op := p.journal.done[len(p.journal.done)-1]
+
+	path, err := p.revert(op)
+	if err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not undo %s: %v", p.describe(op), err))
+		return
+	}
+
+	p.journal.done = p.journal.done[:len(p.journal.done)-1]
+	p.journal.undone = append(p.journal.undone, op)
+
+	p.api.Out.Print(fmt.Sprintf("Undid %s", p.describe(op)))
+	if path != "" {
+		p.reveal(path)
+	}
+}
+
+func (p *FileProvider) redo() {
+	if len(p.journal.undone) == 0 {
+		p.api.Out.Print("Nothing to redo")
+		return
+	}
+
+	op := p.journal.undone[len(p.journal.undone)-1]
+
+	path, err := p.replay(&op)
+	if err != nil {
+		p.api.Out.Print(fmt.Sprintf("Could not redo %s: %v", p.describe(op), err))
+		return
+	}
+
+	p.journal.undone = p.journal.undone[:len(p.journal.undone)-1]
+	p.journal.done = append(p.journal.done, op)
+
+	p.api.Out.Print(fmt.Sprintf("Redid %s", p.describe(op)))
+	if path != "" {
+		p.reveal(path)
+	}
+}
+
+// revert reverses op and returns the path to reveal afterwards.
+func (p *FileProvider) revert(op operation) (string, error) {
+	switch op.kind {
+	case operationCreate, operationCopy:
+		path := op.path
+		if op.kind == operationCopy {
+			path = op.target
+		}
+
+		if _, err := trash(path); err != nil {
+			return "", err
+		}
+		opener.WipeBuffers(p.api, path)
+
+		return "", nil
+
+	case operationMove:
+		if err := p.checkFree(op.path); err != nil {
+			return "", err
+		}
+
+		item, found := p.lookup(op.target)
+		if err := movePath(op.target, op.path); err != nil {
+			return "", err
+		}
+
+		opener.RenameBuffers(p.api, op.target, op.path)
+		if found {
+			p.relocate(item, op.path)
+		}
+
+		return op.path, nil
+
+	case operationTrash:
+		if err := p.checkFree(op.path); err != nil {
+			return "", err
+		}
+
+		if err := untrash(op.target, op.path); err != nil {
+			return "", err
+		}
+
+		return op.path, nil
+	}
+
+	return "", nil
+}
+
+// replay repeats op and returns the path to reveal afterwards.
+func (p *FileProvider) replay(op *operation) (string, error) {
+	switch op.kind {
+	case operationCreate:
+		if err := p.checkFree(op.path); err != nil {
+			return "", err
+		}
+
+		return op.path, createPath(op.path, op.isDir)
+
+	case operationCopy:
+		if err := p.checkFree(op.target); err != nil {
+			return "", err
+		}
+
+		return op.target, copyPath(op.path, op.target)
+
+	case operationMove:
+		if err := p.checkFree(op.target); err != nil {
+			return "", err
+		}
+
+		item, found := p.lookup(op.path)
+		if err := movePath(op.path, op.target); err != nil {
+			return "", err
+		}
+
+		opener.RenameBuffers(p.api, op.path, op.target)
+		if found {
+			p.relocate(item, op.target)
+		}
+
+		return op.target, nil
+
+	case operationTrash:
+		trashPath, err := trash(op.path)
+		if err != nil {
+			return "", err
+		}
+
+		op.target = trashPath
+		opener.WipeBuffers(p.api, op.path)
+
+		return "", nil
+	}
+
+	return "", nil
+}
+
+func (p *FileProvider) checkFree(path string) error {
+	if exists(path) {
+		return fmt.Errorf("%s already exists", p.relPath(path))
+	}
+	return nil
+}
+
+func (p *FileProvider) describe(op operation) string {
+	switch op.kind {
+	case operationCreate:
+		return fmt.Sprintf("create %s", p.relPath(op.path))
+	case operationMove:
+		return fmt.Sprintf("move %s to %s", p.relPath(op.path), p.relPath(op.target))
+	case operationCopy:
+		return fmt.Sprintf("copy %s to %s", p.relPath(op.path), p.relPath(op.target))
+	case operationTrash:
+		return fmt.Sprintf("trash %s", p.relPath(op.path))
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package files
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/josa42/go-neovim"
+	"github.com/josa42/nvim-filetree/pkg/opener"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+func (p *FileProvider) toggleMark(i *FileItem) {
+	if i != p.root {
+		i.isMarked = !i.isMarked
+	}
+}
+
+// MarkRange toggles the marks of all items between the lines of the last
+// visual selection.
+func (p *FileProvider) MarkRange() {
+	from, to := 0, 0
+	p.api.Eval(`line("'<")`, &from)
+	p.api.Eval(`line("'>")`, &to)
+
+	p.updateVisibleItems()
+
+	for idx, i := range p.visibleItems {
+		if line := idx + 1; line >= from && line <= to {
+			p.toggleMark(i)
+		}
+	}
+
+	p.triggerChange()
+}
+
+func (p *FileProvider) clearMarks() {
+	for _, i := range p.markedItems() {
+		i.isMarked = false
+	}
+}
+
+// markedItems returns all marked items, including those in closed
+// directories.
+func (p *FileProvider) markedItems() []*FileItem {
+	items := []*FileItem{}
+
+	var walk func(i *FileItem)
+	walk = func(i *FileItem) {
+		for _, c := range i.children {
+			if child, ok := c.(*FileItem); ok {
+				if child.isMarked {
+					items = append(items, child)
+				}
+				walk(child)
+			}
+		}
+	}
+	walk(p.root)
+
+	return items
+}
+
+// selection returns the marked items, or i if nothing is marked.
+func (p *FileProvider) selection(i *FileItem) []*FileItem {
+	if items := p.markedItems(); len(items) > 0 {
+		return items
+	}
+	return []*FileItem{i}
+}
+
+// topLevelItems returns the items that are not below another one of items.
+// Those are moved or copied along with their parent.
+func topLevelItems(items []*FileItem) []*FileItem {
+	top := []*FileItem{}
+
+	for _, i := range items {
+		nested := false
+		for _, o := range items {
+			if o != i && isBelow(i.path, o.path) {
+				nested = true
+				break
+			}
+		}
+
+		if !nested {
+			top = append(top, i)
+		}
+	}
+
+	return top
+}
+
+func (p *FileProvider) open(items []*FileItem, open func(*neovim.Api, string)) {
+	for _, i := range items {
+		if !i.isDir {
+			open(p.api, i.path)
+		}
+	}
+
+	p.clearMarks()
+}
+
+func (p *FileProvider) sendToQuickfix(items []*FileItem) {
+	entries := []string{}
+	for _, i := range items {
+		if !i.isDir {
+			entries = append(entries, fmt.Sprintf("{'filename': %s}", prompt.Quote(i.path)))
+		}
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	p.api.Executef("call setqflist([], ' ', {'title': 'Tree', 'items': [%s]})", strings.Join(entries, ", "))
+
+	opener.FocusEditor(p.api)
+	p.api.Execute("copen")
+
+	p.clearMarks()
+}
@@ -0,0 +1,68 @@
+package files
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+const (
+	GlobalVarIgnore     = "tree_ignore"
+	GlobalVarAlwaysShow = "tree_always_show"
+)
+
+// defaultIgnore is always applied, g:tree_ignore adds to it. Entries can be
+// shown again with g:tree_always_show.
+var defaultIgnore = []string{".git", ".DS_Store"}
+
+// patternList holds glob patterns from a global variable. The patterns use
+// the gitignore syntax and are relative to the tree root.
+type patternList struct {
+	key      string
+	patterns []ignorePattern
+}
+
+func (l *patternList) update(globs []string, base string) {
+	key := base + "\x00" + strings.Join(globs, "\x00")
+	if key == l.key {
+		return
+	}
+
+	l.key = key
+	l.patterns = []ignorePattern{}
+
+	for _, glob := range globs {
+		if pattern, ok := parseIgnorePattern(glob, base); ok {
+			l.patterns = append(l.patterns, pattern)
+		}
+	}
+}
+
+func (l *patternList) match(path string, isDir bool) bool {
+	for _, pattern := range l.patterns {
+		if pattern.match(path, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FileProvider) updatePatterns() {
+	ignore := append(append([]string{}, defaultIgnore...), p.globalList(GlobalVarIgnore, []string{})...)
+
+	p.ignorePatterns.update(ignore, p.root.path)
+	p.alwaysShowPatterns.update(p.globalList(GlobalVarAlwaysShow, []string{}), p.root.path)
+}
+
+func (p *FileProvider) globalList(name string, def []string) []string {
+	quoted := make([]string, len(def))
+	for idx, d := range def {
+		quoted[idx] = prompt.Quote(d)
+	}
+
+	list := []string{}
+	p.api.Eval(fmt.Sprintf("get(g:, %s, [%s])", prompt.Quote(name), strings.Join(quoted, ", ")), &list)
+
+	return list
+}
@@ -1,17 +1,20 @@
 package files
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/josa42/go-gitignore"
 	"github.com/josa42/go-neovim"
 	"github.com/josa42/go-neovim/view"
 	"github.com/josa42/nvim-filetree/pkg/actions"
 	"github.com/josa42/nvim-filetree/pkg/opener"
 )
 
+const gitDirName = ".git"
+
 // Interface Assertions
 var _ view.TreeProvider = (*FileProvider)(nil)
 var _ neovim.Updatable = (*FileProvider)(nil)
@@ -22,20 +25,48 @@ type FileProvider struct {
 	api           *neovim.Api
 	root          *FileItem
 	visibleItems  []*FileItem
-	gitignore     gitignore.Gitignore
+	ignore        *ignoreMatcher
 	changeTrigger *func()
 	fileStatus    statusMap
+	clipboard     clipboard
+	journal       journal
+	watcher       watcher
+	gitAvailable  bool
+	gitDir        string
+	gitDirRoot    string
+	gitDirChecked time.Time
+	showHidden    bool
+	showIgnored   bool
+	showDetails   bool
+	detailsWidth  int
+	compactDirs   bool
+
+	ignorePatterns     patternList
+	alwaysShowPatterns patternList
+	filter             *filterState
+	pinnedRoot         string
+	restoreOpen        map[string]bool
+	sortOrder          sortOrder
+	iconTheme          iconTheme
+	fileIcons          map[string]fileIcon
+	syntax             []string
 }
 
 func NewFileProvider(api *neovim.Api) *FileProvider {
 	root := &FileItem{}
 
 	p := &FileProvider{
-		api:  api,
-		root: root,
+		api:        api,
+		root:       root,
+		showHidden: true,
 	}
 
 	root.provider = p
+	p.showDetails = api.Global.Vars.Bool(GlobalVarDetails)
+	p.compactDirs = p.loadCompactDirs()
+	p.sortOrder = p.loadSortOrder()
+	p.iconTheme = p.loadIconTheme()
+	p.fileIcons = p.loadFileIcons()
 
 	return p
 }
@@ -58,24 +89,212 @@ func (p *FileProvider) Update() {
 	}()
 
 	p.updateRootPath()
+	p.updateIgnore()
+	p.updatePatterns()
+	p.updateWatches()
+	p.updateDetailsWidth()
+	p.updateIconTheme()
+}
 
-	// TODO refactor gitignore handling
-	p.gitignore, _ = gitignore.NewGitignoreFromFile(filepath.Join(p.root.path, ".gitignore"))
+func (p *FileProvider) updateIgnore() {
+	if p.ignore == nil || p.ignore.dir != p.root.path {
+		p.ignore = newIgnoreMatcher(p.root.path)
+	}
 }
 
 func (p *FileProvider) updateRootPath() bool {
-	path := p.api.Cwd()
-	if p.root.path != path {
-		p.root.path = path
+	path := p.pinnedRoot
+	if path == "" {
+		path = p.api.Cwd()
+	}
 
-		return true
+	return p.setRootPath(path)
+}
+
+func (p *FileProvider) isIgnored(path string, isDir bool) bool {
+	return p.ignorePatterns.match(path, isDir) || p.ignore != nil && p.ignore.match(path, isDir)
+}
+
+func (p *FileProvider) isAlwaysShown(path string, isDir bool) bool {
+	return p.alwaysShowPatterns.match(path, isDir)
+}
+
+// visibility reports whether an entry is ignored and whether it is shown with
+// the active filters.
+func (p *FileProvider) visibility(path string, isDir, isGhost bool) (bool, bool) {
+	// The git directory is never shown, not even with ignored files
+	if filepath.Base(path) == gitDirName {
+		return true, false
+	}
+
+	alwaysShown := p.isAlwaysShown(path, isDir)
+
+	ignored := !isGhost && !alwaysShown && (p.isIgnored(path, isDir) || p.fileStatus.get(path, false) == FileStatusIgnored)
+
+	if ignored && !p.showIgnored {
+		return ignored, false
+	}
+
+	if isHidden(filepath.Base(path)) && !alwaysShown && !p.showHidden {
+		return ignored, false
+	}
+
+	return ignored, true
+}
+
+// Title describes the active filters.
+func (p *FileProvider) Title() string {
+	hidden := []string{}
+	if !p.showHidden {
+		hidden = append(hidden, "dotfiles")
+	}
+	if !p.showIgnored {
+		hidden = append(hidden, "ignored")
+	}
+
+	if len(hidden) == 0 {
+		return p.iconTheme.title
+	}
+
+	return fmt.Sprintf("%s  hiding %s", p.iconTheme.title, strings.Join(hidden, ", "))
+}
+
+func (p *FileProvider) updateTitle() {
+	if b, found := p.api.FindBuffer(func(b *neovim.Buffer) bool {
+		return b.Vars.Bool("is_tree")
+	}); found {
+		b.SetTitle(p.Title())
+	}
+}
+
+func (p *FileProvider) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(p.root.path, path)
+}
+
+func (p *FileProvider) relPath(path string) string {
+	if pr, err := filepath.Rel(p.root.path, path); err == nil {
+		return pr
+	}
+	return path
+}
+
+// find returns the item for path, opening all its ancestor directories.
+func (p *FileProvider) find(path string) (*FileItem, bool) {
+	pr, err := filepath.Rel(p.root.path, path)
+	if err != nil || strings.HasPrefix(pr, "..") {
+		return nil, false
+	}
+
+	item := p.root
+	if pr == "." {
+		return item, true
+	}
+
+	for _, name := range strings.Split(pr, string(filepath.Separator)) {
+		// Directories inside a compacted chain are shown as open already
+		if item != p.root && !item.inChain {
+			item.isOpen = true
+		}
+
+		found := false
+		for _, c := range item.list() {
+			if child, ok := c.(*FileItem); ok && child.name == name {
+				item = child
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return item, true
+}
+
+// lookup returns the already loaded item for path, without reading any
+// directory or opening its ancestors.
+func (p *FileProvider) lookup(path string) (*FileItem, bool) {
+	item := p.root
+
+	for item.path != path {
+		if !isBelow(path, item.path) {
+			return nil, false
+		}
+
+		found := false
+		for _, c := range item.children {
+			if child, ok := c.(*FileItem); ok && (child.path == path || isBelow(path, child.path)) {
+				item = child
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return item, true
+}
+
+// Reveal opens all ancestors of path and moves the tree cursor onto it.
+func (p *FileProvider) Reveal(path string) {
+	if path == "" || !isBelow(path, p.root.path) {
+		return
 	}
+
+	if p.reveal(path) {
+		p.triggerChange()
+	}
+}
+
+// reveal opens all ancestors of path and moves the tree cursor onto it.
+func (p *FileProvider) reveal(path string) bool {
+	item, found := p.find(path)
+	if !found || item == p.root {
+		return false
+	}
+
+	p.updateVisibleItems()
+
+	for idx, v := range p.visibleItems {
+		if v.shows(item.path) {
+			p.setCursor(idx + 1)
+			return true
+		}
+	}
+
 	return false
 }
 
-func (p *FileProvider) isIgnored(path string) bool {
-	pr, _ := filepath.Rel(p.root.path, path)
-	return p.gitignore.Match(pr)
+func (p *FileProvider) updateVisibleItems() {
+	items := []*FileItem{}
+
+	var walk func(i *FileItem)
+	walk = func(i *FileItem) {
+		for _, c := range i.Children() {
+			if child, ok := c.(*FileItem); ok {
+				items = append(items, child)
+				if child.isDir && child.IsOpen() {
+					walk(child)
+				}
+			}
+		}
+	}
+	walk(p.root)
+
+	p.visibleItems = items
+}
+
+// setCursor moves the cursor of the tree window once the tree is rendered.
+func (p *FileProvider) setCursor(line int) {
+	p.api.Executef("call timer_start(0, {-> nvim_win_set_cursor(bufwinid(g:tree_buffer_id), [%d, 0])})", line)
 }
 
 func (p *FileProvider) status(path string) rune {
@@ -103,6 +322,35 @@ func (p *FileProvider) Actions() []view.TreeAction {
 		{Keys: "t", Handler: handler(actions.OpenTab)},
 		{Keys: "v", Handler: handler(actions.OpenVerticalSplit)},
 		{Keys: "s", Handler: handler(actions.OpenHorizontalSplit)},
+		{Keys: "a", Handler: handler(actions.New)},
+		{Keys: "r", Handler: handler(actions.Rename)},
+		{Keys: "d", Handler: handler(actions.Delete)},
+		{Keys: "x", Handler: handler(actions.Cut)},
+		{Keys: "c", Handler: handler(actions.Copy)},
+		{Keys: "p", Handler: handler(actions.Paste)},
+		{Keys: "m", Handler: handler(actions.ToggleMark)},
+		{Keys: "M", Handler: handler(actions.ClearMarks)},
+		{Keys: "q", Handler: handler(actions.Quickfix)},
+		{Keys: "R", Handler: handler(actions.Restore)},
+		{Keys: "gs", Handler: handler(actions.Stage)},
+		{Keys: "gu", Handler: handler(actions.Unstage)},
+		{Keys: "gr", Handler: handler(actions.Discard)},
+		{Keys: "gd", Handler: handler(actions.Diff)},
+		{Keys: "gD", Handler: handler(actions.DiffSplit)},
+		{Keys: "H", Handler: handler(actions.ToggleHidden)},
+		{Keys: "I", Handler: handler(actions.ToggleIgnored)},
+		{Keys: "/", Handler: handler(actions.Filter)},
+		{Keys: "C", Handler: handler(actions.RootToItem)},
+		{Keys: "-", Handler: handler(actions.RootUp)},
+		{Keys: "~", Handler: handler(actions.RootToCwd)},
+		{Keys: "B", Handler: handler(actions.Bookmark)},
+		{Keys: "gb", Handler: handler(actions.JumpToBookmark)},
+		{Keys: "gB", Handler: handler(actions.RemoveBookmark)},
+		{Keys: "S", Handler: handler(actions.CycleSort)},
+		{Keys: "gS", Handler: handler(actions.ToggleDirsFirst)},
+		{Keys: "i", Handler: handler(actions.ToggleDetails)},
+		{Keys: "u", Handler: handler(actions.Undo)},
+		{Keys: "<C-r>", Handler: handler(actions.Redo)},
 		{Keys: "<ESC>", Handler: handler(actions.Unfocus)},
 		{Keys: "h", Handler: handler(actions.Help)},
 	}
@@ -128,22 +376,114 @@ func (p *FileProvider) handleAction(i *FileItem, action string) {
 		}
 
 	case actions.Open:
-		opener.Open(p.api, i.path)
+		p.open(p.selection(i), opener.Open)
 
 	case actions.OpenTab:
-		opener.OpenTab(p.api, i.path)
+		p.open(p.selection(i), opener.OpenTab)
 
 	case actions.OpenHorizontalSplit:
-		opener.OpenHoricontalSplit(p.api, i.path)
+		p.open(p.selection(i), opener.OpenHoricontalSplit)
 
 	case actions.OpenVerticalSplit:
-		opener.OpenVerticalSplit(p.api, i.path)
+		p.open(p.selection(i), opener.OpenVerticalSplit)
+
+	case actions.New:
+		p.create(i)
+
+	case actions.Rename:
+		p.rename(p.selection(i))
+
+	case actions.Delete:
+		p.delete(p.selection(i))
+
+	case actions.Cut:
+		p.copyToClipboard(p.selection(i), true)
+
+	case actions.Copy:
+		p.copyToClipboard(p.selection(i), false)
+
+	case actions.Paste:
+		p.paste(i)
+
+	case actions.ToggleMark:
+		p.toggleMark(i)
+
+	case actions.ClearMarks:
+		p.clearMarks()
+
+	case actions.Quickfix:
+		p.sendToQuickfix(p.selection(i))
+
+	case actions.Restore:
+		p.restoreDeleted(i)
+
+	case actions.Stage:
+		p.stage(i)
+
+	case actions.Unstage:
+		p.unstage(i)
+
+	case actions.Discard:
+		p.discard(i)
+
+	case actions.Diff:
+		p.diff(i)
+
+	case actions.DiffSplit:
+		p.diffSplit(i)
+
+	case actions.ToggleHidden:
+		p.showHidden = !p.showHidden
+		p.updateTitle()
+
+	case actions.ToggleIgnored:
+		p.showIgnored = !p.showIgnored
+		p.updateTitle()
+
+	case actions.Filter:
+		p.runFilter()
+
+	case actions.RootToItem:
+		p.rootToItem(i)
+
+	case actions.RootUp:
+		p.rootUp()
+
+	case actions.RootToCwd:
+		p.rootToCwd()
+
+	case actions.Bookmark:
+		p.addBookmark(i)
+
+	case actions.JumpToBookmark:
+		p.jumpToBookmark()
+
+	case actions.RemoveBookmark:
+		p.removeBookmark()
+
+	case actions.CycleSort:
+		p.sortOrder = p.sortOrder.next()
+		p.api.Out.Print(fmt.Sprintf("Sort by %s", p.sortOrder))
+
+	case actions.ToggleDirsFirst:
+		p.sortOrder.dirsFirst = !p.sortOrder.dirsFirst
+		p.api.Out.Print(fmt.Sprintf("Sort by %s", p.sortOrder))
+
+	case actions.ToggleDetails:
+		p.showDetails = !p.showDetails
+		p.updateDetailsWidth()
+
+	case actions.Undo:
+		p.undo()
+
+	case actions.Redo:
+		p.redo()
 
 	case actions.Unfocus:
 		opener.FocusEditor(p.api)
 
 	case actions.Help:
-		p.api.Out.Print("?: Help - (o)pen - (e)dit - (t)ab - (s)plit - (v)ertical split - ESC unfocus")
+		p.api.Out.Print("?: Help - (o)pen - (e)dit - (t)ab - (s)plit - (v)ertical split - (a)dd - (r)ename - (d)elete - cut (x) - (c)opy - (p)aste - (m)ark - clear (M)arks - (q)uickfix - (R)estore deleted - (gs) stage - (gu) unstage - (gr) discard - (gd) diff - (gD) diff split - (H)idden - (I)gnored - (/) filter - (C)hange root - (-) parent root - (~) cwd root - (B)ookmark - (gb) bookmarks - (gB) remove bookmark - (S)ort - (gS) directories first - (i) details - (u)ndo - CTRL-R redo - ESC unfocus")
 	}
 }
 
@@ -156,53 +496,143 @@ func (p *FileProvider) Listen(changed func()) {
 
 func (p *FileProvider) Unlisten() {
 	p.changeTrigger = nil
+
+	if p.watcher != nil {
+		p.watcher.close()
+		p.watcher = nil
+	}
+}
+
+// Refresh updates the root path and the git status, e.g. after the working
+// directory changed.
+func (p *FileProvider) Refresh() {
+	p.updateRootPath()
+	p.updateIgnore()
+	p.updateWatches()
+
+	if p.gitAvailable {
+		p.updateFileStatus()
+	}
+
+	p.triggerChange()
+}
+
+func (p *FileProvider) triggerChange() {
+	if p.changeTrigger != nil {
+		t := *p.changeTrigger
+		t()
+	}
 }
 
 func (p *FileProvider) runChangeListener() {
+	w, err := newWatcher()
+	if err != nil {
+		log.Printf("watcher - err: %v, falling back to polling", err)
+		w = newPollWatcher()
+	}
 
-	go func() {
-		gitAvailable := isGitAvailable()
+	p.watcher = w
+	p.updateWatches()
 
-		nextGitRun := time.Now()
+	go func() {
+		p.gitAvailable = isGitAvailable()
 
-		// TODO Fix this! Listen tochangechanges instead?
-		for {
-			time.Sleep(1 * time.Second)
-			if p.changeTrigger == nil {
-				break
-			}
+		if p.gitAvailable && p.updateFileStatus() {
+			p.triggerChange()
+		}
 
-			pc := p.updateRootPath()
+		if pw, ok := w.(*pollWatcher); ok {
+			go p.pollFileStatus(pw)
+		}
 
-			sc := false
-			if gitAvailable {
-				sc, nextGitRun = p.updateFileStatus(nextGitRun)
+		for range debounce(w.events(), watchDelay) {
+			if p.ignore != nil {
+				p.ignore.reset()
 			}
 
-			if pc || sc {
-				t := *p.changeTrigger
-				t()
+			if p.gitAvailable {
+				p.updateFileStatus()
 			}
+
+			p.triggerChange()
 		}
 	}()
 }
 
-func (p *FileProvider) updateFileStatus(nextRun time.Time) (bool, time.Time) {
+// pollFileStatus updates the git status periodically, as long as the polling
+// watcher is running.
+func (p *FileProvider) pollFileStatus(w *pollWatcher) {
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-time.After(pollStatusInterval):
+		}
+
+		if p.gitAvailable && p.updateFileStatus() {
+			p.triggerChange()
+		}
+	}
+}
 
-	if nextRun.After(time.Now()) {
-		return false, nextRun
+// updateWatches watches the root, all open directories, the git directory and
+// the directories of global ignore files for changes.
+func (p *FileProvider) updateWatches() {
+	if p.watcher == nil {
+		return
 	}
 
-	if !isGitRepo(p.root.path) {
-		return false, time.Now().Add(30 * time.Second)
+	dirs := []string{p.root.path}
+	if dir, ok := p.repoGitDir(); ok && isDir(dir) {
+		dirs = append(dirs, dir)
+	}
+
+	if p.ignore != nil {
+		for _, file := range p.ignore.files() {
+			if dir := filepath.Dir(file); isDir(dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	var walk func(i *FileItem)
+	walk = func(i *FileItem) {
+		for _, c := range i.children {
+			if child, ok := c.(*FileItem); ok && child.isDir && child.IsOpen() {
+				dirs = append(dirs, child.path)
+				walk(child)
+			}
+		}
+	}
+	walk(p.root)
+
+	p.watcher.set(dirs)
+}
+
+// repoGitDir returns the git directory of the root. It is looked up once per
+// root, a root outside of a repository is checked again after
+// gitRecheckInterval.
+func (p *FileProvider) repoGitDir() (string, bool) {
+	if p.gitDirRoot != p.root.path || p.gitDir == "" && time.Since(p.gitDirChecked) > gitRecheckInterval {
+		p.gitDirRoot = p.root.path
+		p.gitDir, _ = gitDir(p.root.path)
+		p.gitDirChecked = time.Now()
+	}
+
+	return p.gitDir, p.gitDir != ""
+}
+
+func (p *FileProvider) updateFileStatus() bool {
+	if _, ok := p.repoGitDir(); !ok {
+		return false
 	}
 
 	fs := updateStatus(p.root.path)
 
 	if p.fileStatus.hashChanges(fs) {
 		p.fileStatus = fs
-		return true, time.Now().Add(5 * time.Second)
+		return true
 	}
 
-	return false, time.Now().Add(5 * time.Second)
+	return false
 }
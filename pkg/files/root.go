@@ -0,0 +1,103 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/josa42/go-neovim/view"
+	"github.com/josa42/nvim-filetree/pkg/opener"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+// GlobalVarRootCd names the command ("cd", "tcd" or "lcd") used to change the
+// working directory along with the root. The working directory is kept if it
+// is not set.
+const GlobalVarRootCd = "tree_root_cd"
+
+// setRootPath moves the root to path. The open state of directories that are
+// still part of the tree is kept.
+func (p *FileProvider) setRootPath(path string) bool {
+	if p.root.path == path {
+		return false
+	}
+
+	p.SaveOpenDirs()
+	p.restoreOpen = p.loadOpenDirs(path)
+
+	var children []view.TreeItem
+
+	if item, found := p.lookup(path); found {
+		children = item.children
+
+	} else if p.root.path != "" && isBelow(p.root.path, path) {
+		child := &FileItem{
+			name:     filepath.Base(p.root.path),
+			path:     p.root.path,
+			isDir:    true,
+			isOpen:   true,
+			children: p.root.children,
+			provider: p,
+		}
+
+		for dir := filepath.Dir(child.path); dir != path; dir = filepath.Dir(dir) {
+			child = &FileItem{
+				name:     filepath.Base(dir),
+				path:     dir,
+				isDir:    true,
+				isOpen:   true,
+				children: []view.TreeItem{child},
+				provider: p,
+			}
+		}
+
+		children = []view.TreeItem{child}
+	}
+
+	p.root.path = path
+	p.root.children = children
+
+	return true
+}
+
+// changeRoot pins the root to path, decoupled from the working directory.
+func (p *FileProvider) changeRoot(path string) {
+	if !isDir(path) {
+		p.api.Out.Print(fmt.Sprintf("%s is not a directory", path))
+		return
+	}
+
+	p.pinnedRoot = path
+	p.updateRootPath()
+	p.updateIgnore()
+
+	cmd := ""
+	p.api.Eval(fmt.Sprintf("get(g:, %s, '')", prompt.Quote(GlobalVarRootCd)), &cmd)
+
+	switch cmd {
+	case "cd", "tcd", "lcd":
+		opener.ChangeDir(p.api, cmd, path)
+	}
+}
+
+func (p *FileProvider) rootToItem(i *FileItem) {
+	if i.isDir {
+		p.changeRoot(i.path)
+	} else {
+		p.changeRoot(filepath.Dir(i.path))
+	}
+}
+
+func (p *FileProvider) rootUp() {
+	if parent := filepath.Dir(p.root.path); parent != p.root.path {
+		prev := p.root.path
+		p.changeRoot(parent)
+		p.reveal(prev)
+	}
+}
+
+// rootToCwd couples the root to the working directory again.
+func (p *FileProvider) rootToCwd() {
+	p.pinnedRoot = ""
+	p.updateRootPath()
+	p.updateIgnore()
+}
@@ -0,0 +1,156 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+const (
+	GlobalVarSort          = "tree_sort"
+	GlobalVarSortDirsFirst = "tree_sort_dirs_first"
+)
+
+const (
+	SortName            = "name"
+	SortNatural         = "natural"
+	SortCaseInsensitive = "case"
+	SortExtension       = "extension"
+	SortModified        = "mtime"
+	SortSize            = "size"
+)
+
+var sortModes = []string{SortName, SortNatural, SortCaseInsensitive, SortExtension, SortModified, SortSize}
+
+type sortOrder struct {
+	mode      string
+	dirsFirst bool
+}
+
+func (p *FileProvider) loadSortOrder() sortOrder {
+	order := sortOrder{mode: SortName, dirsFirst: true}
+
+	mode := ""
+	p.api.Eval(fmt.Sprintf("get(g:, %s, '')", prompt.Quote(GlobalVarSort)), &mode)
+	for _, m := range sortModes {
+		if m == mode {
+			order.mode = mode
+		}
+	}
+
+	dirsFirst := 1
+	p.api.Eval(fmt.Sprintf("get(g:, %s, 1)", prompt.Quote(GlobalVarSortDirsFirst)), &dirsFirst)
+	order.dirsFirst = dirsFirst != 0
+
+	return order
+}
+
+func (o sortOrder) next() sortOrder {
+	for idx, m := range sortModes {
+		if m == o.mode {
+			o.mode = sortModes[(idx+1)%len(sortModes)]
+			return o
+		}
+	}
+
+	o.mode = SortName
+	return o
+}
+
+func (o sortOrder) String() string {
+	if o.dirsFirst {
+		return fmt.Sprintf("%s, directories first", o.mode)
+	}
+	return o.mode
+}
+
+func (o sortOrder) less(a, b *FileItem) bool {
+	if o.dirsFirst && a.isDir != b.isDir {
+		return a.isDir
+	}
+
+	switch o.mode {
+	case SortNatural:
+		if c := naturalCompare(a.name, b.name); c != 0 {
+			return c < 0
+		}
+
+	case SortCaseInsensitive:
+		if la, lb := strings.ToLower(a.name), strings.ToLower(b.name); la != lb {
+			return la < lb
+		}
+
+	case SortExtension:
+		if ea, eb := strings.ToLower(filepath.Ext(a.name)), strings.ToLower(filepath.Ext(b.name)); ea != eb {
+			return ea < eb
+		}
+
+	case SortModified:
+		if ma, mb := modTime(a), modTime(b); !ma.Equal(mb) {
+			return ma.After(mb)
+		}
+
+	case SortSize:
+		if sa, sb := size(a), size(b); sa != sb {
+			return sa > sb
+		}
+	}
+
+	return a.name < b.name
+}
+
+func size(i *FileItem) int64 {
+	if fi := i.stat(); fi != nil {
+		return fi.Size()
+	}
+	return 0
+}
+
+func modTime(i *FileItem) time.Time {
+	if fi := i.stat(); fi != nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
+
+// naturalCompare compares a and b with runs of digits compared by their
+// numeric value, so "file2" sorts before "file10".
+func naturalCompare(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	ia, ib := 0, 0
+
+	for ia < len(ra) && ib < len(rb) {
+		if unicode.IsDigit(ra[ia]) && unicode.IsDigit(rb[ib]) {
+			sa, sb := ia, ib
+			for ia < len(ra) && unicode.IsDigit(ra[ia]) {
+				ia++
+			}
+			for ib < len(rb) && unicode.IsDigit(rb[ib]) {
+				ib++
+			}
+
+			na := strings.TrimLeft(string(ra[sa:ia]), "0")
+			nb := strings.TrimLeft(string(rb[sb:ib]), "0")
+
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if na != nb {
+				return strings.Compare(na, nb)
+			}
+			continue
+		}
+
+		if ra[ia] != rb[ib] {
+			return int(ra[ia]) - int(rb[ib])
+		}
+		ia++
+		ib++
+	}
+
+	return (len(ra) - ia) - (len(rb) - ib)
+}
@@ -1,13 +1,14 @@
 package files
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -16,16 +17,35 @@ const (
 	FileStatusChanged
 	FileStatusUntracked
 	FileStatusConflicted
+	FileStatusDeleted
+	FileStatusStaged
+	FileStatusPartiallyStaged
 )
 
+// Columns of the status code
+const (
+	indexColumn    = 0
+	workTreeColumn = 1
+)
+
+// gitRecheckInterval is the delay after which a directory outside of a git
+// repository is checked again.
+const gitRecheckInterval = 30 * time.Second
+
 var (
-	expStatusLine    = regexp.MustCompile(`^(..) (.*)$`)
 	expTrailingSlash = regexp.MustCompile(`/*$`)
 )
 
 type status int
 
-type statusMap map[string]status
+// fileStatus is the status of a file along with its porcelain status code,
+// the index (X) and work tree (Y) columns.
+type fileStatus struct {
+	status status
+	code   string
+}
+
+type statusMap map[string]fileStatus
 
 func (s statusMap) get(path string, dir bool) status {
 
@@ -36,15 +56,26 @@ func (s statusMap) get(path string, dir bool) status {
 			return FileStatusConflicted
 		}
 
-		if s.dirContains(path, FileStatusChanged) {
+		staged := s.dirContains(path, FileStatusStaged) || s.dirContainsDeleted(path, indexColumn)
+		unstaged := s.dirContains(path, FileStatusChanged) || s.dirContainsDeleted(path, workTreeColumn)
+
+		if s.dirContains(path, FileStatusPartiallyStaged) || staged && unstaged {
+			return FileStatusPartiallyStaged
+		}
+
+		if unstaged {
 			return FileStatusChanged
 		}
 
+		if staged {
+			return FileStatusStaged
+		}
+
 		if s.dirContains(path, FileStatusUntracked) {
 			return FileStatusUntracked
 		}
 	} else if fs, ok := s[path]; ok {
-		return fs
+		return fs.status
 	}
 	return FileStatusNormal
 }
@@ -52,7 +83,7 @@ func (s statusMap) get(path string, dir bool) status {
 func (s statusMap) dirContains(path string, fileStatus status) bool {
 	path = expTrailingSlash.ReplaceAllString(path, "/")
 	for p, fs := range s {
-		if fs == fileStatus && strings.HasPrefix(p, path) {
+		if fs.status == fileStatus && strings.HasPrefix(p, path) {
 			return true
 		}
 	}
@@ -60,11 +91,80 @@ func (s statusMap) dirContains(path string, fileStatus status) bool {
 	return false
 }
 
+// dirContainsDeleted reports whether a deleted file below path has a change in
+// the given column of the status code, e.g. "MD" is staged and unstaged.
+func (s statusMap) dirContainsDeleted(path string, column int) bool {
+	path = expTrailingSlash.ReplaceAllString(path, "/")
+	for p, fs := range s {
+		if fs.status == FileStatusDeleted && fs.code[column] != ' ' && strings.HasPrefix(p, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deletionStaged reports whether the deletion of path, or of all deleted files
+// below it, is staged completely.
+func (s statusMap) deletionStaged(path string) bool {
+	path = strings.TrimRight(path, `/`)
+	found := false
+
+	for p, fs := range s {
+		if fs.status != FileStatusDeleted || p != path && !isBelow(p, path) {
+			continue
+		}
+		if fs.code[workTreeColumn] != ' ' {
+			return false
+		}
+		found = true
+	}
+
+	return found
+}
+
+// deleted returns the names of deleted files and directories directly below
+// dir, mapped to whether they are a directory.
+func (s statusMap) deleted(dir string) map[string]bool {
+	names := map[string]bool{}
+
+	for p, fs := range s {
+		if fs.status != FileStatusDeleted || !isBelow(p, dir) {
+			continue
+		}
+
+		name := strings.TrimPrefix(p, dir+string(filepath.Separator))
+		parent := false
+		if idx := strings.IndexRune(name, filepath.Separator); idx >= 0 {
+			name, parent = name[:idx], true
+		}
+
+		names[name] = names[name] || parent
+	}
+
+	return names
+}
+
+// code returns the status code of path, "  " for unmodified files.
+func (s statusMap) code(path string) string {
+	if fs, ok := s[strings.TrimRight(path, `/`)]; ok {
+		return fs.code
+	}
+	return "  "
+}
+
 func (s statusMap) hashChanges(s2 statusMap) bool {
-	j1, _ := json.Marshal(s)
-	j2, _ := json.Marshal(s2)
+	if len(s) != len(s2) {
+		return true
+	}
 
-	return string(j1) != string(j2)
+	for path, fs := range s {
+		if fs2, ok := s2[path]; !ok || fs2 != fs {
+			return true
+		}
+	}
+
+	return false
 }
 
 func updateStatus(dir string) statusMap {
@@ -82,7 +182,7 @@ func updateStatus(dir string) statusMap {
 
 	rootDir := strings.TrimSpace(string(rootDirB))
 
-	cmdStatus := git("status", "--porcelain", "--ignored")
+	cmdStatus := git("status", "--porcelain=v2", "-z", "--ignored")
 	cmdStatus.Dir = dir
 	out, err := cmdStatus.Output()
 	if err != nil {
@@ -90,11 +190,59 @@ func updateStatus(dir string) statusMap {
 		return s
 	}
 
-	for _, l := range strings.Split(string(out), "\n") {
-		p := expStatusLine.FindStringSubmatch(l)
-		if len(p) > 0 {
-			path := strings.TrimRight(filepath.Join(rootDir, p[2]), `/`)
-			s[path] = getStatus(p[1])
+	return parseStatus(rootDir, string(out))
+}
+
+// parseStatus parses the output of `git status --porcelain=v2 -z`. Paths are
+// relative to rootDir.
+//
+// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>\0<origPath>
+// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+// ? <path>
+// ! <path>
+func parseStatus(rootDir, out string) statusMap {
+	s := statusMap{}
+
+	entries := strings.Split(out, "\x00")
+
+	for n := 0; n < len(entries); n++ {
+		entry := entries[n]
+		if len(entry) < 3 {
+			continue
+		}
+
+		xy, path := "", ""
+
+		switch entry[0] {
+		case '1':
+			if f := strings.SplitN(entry, " ", 9); len(f) == 9 {
+				xy, path = f[1], f[8]
+			}
+
+		case '2':
+			if f := strings.SplitN(entry, " ", 10); len(f) == 10 {
+				xy, path = f[1], f[9]
+			}
+			// The original path of a rename or copy is a separate entry
+			n++
+
+		case 'u':
+			if f := strings.SplitN(entry, " ", 11); len(f) == 11 {
+				xy, path = f[1], f[10]
+			}
+
+		case '?':
+			xy, path = "??", entry[2:]
+
+		case '!':
+			xy, path = "!!", entry[2:]
+		}
+
+		if path != "" {
+			// Porcelain v2 uses "." for unmodified, v1 used " "
+			xy = strings.ReplaceAll(xy, ".", " ")
+			s[strings.TrimRight(filepath.Join(rootDir, path), `/`)] = fileStatus{status: getStatus(xy), code: xy}
 		}
 	}
 
@@ -112,18 +260,42 @@ func isGitAvailable() bool {
 	return err == nil
 }
 
-func isGitRepo(dir string) bool {
+// gitDir returns the git directory of the repository containing dir, which
+// does not need to be the top level of the work tree.
+func gitDir(dir string) (string, bool) {
+	cmd := git("rev-parse", "--git-dir")
+	cmd.Dir = dir
 
-	gitPath := filepath.Join(dir, ".git")
-	if _, err := os.Stat(gitPath); err == nil {
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false
+	}
+
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return path, true
+}
 
-		cmd := git("status")
-		cmd.Dir = dir
-		err := cmd.Run()
+// gitPath resolves a path inside the git directory of the repository at dir,
+// e.g. info/exclude, which also takes worktrees and submodules into account.
+func gitPath(dir, name string) (string, bool) {
+	cmd := git("rev-parse", "--git-path", name)
+	cmd.Dir = dir
 
-		return err == nil
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false
 	}
-	return false
+
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return path, true
 }
 
 // ' ' = unmodified
@@ -147,7 +319,7 @@ var (
 	expChanged = regexp.MustCompile(`^( [AMD]|M[ MD]|A[ MD]|R[ MD]|C[ MD]|[MARC] |[ MARC]M|[ D]R|[ D]C)$`)
 
 	// [ MARC]D = deleted in work tree
-	// D        =deleted from index
+	// D        = deleted from index
 	expDeleted = regexp.MustCompile(`^([ MARC]D|D )$`)
 
 	// DD = unmerged, both deleted
@@ -180,8 +352,12 @@ func getStatus(m string) status {
 		return FileStatusIgnored
 	}
 
+	if expDeleted.MatchString(m) {
+		return FileStatusDeleted
+	}
+
 	if expChanged.MatchString(m) {
-		return FileStatusChanged
+		return getChangedStatus(m)
 	}
 
 	if m != "  " {
@@ -190,6 +366,65 @@ func getStatus(m string) status {
 	return FileStatusNormal
 }
 
+// getChangedStatus tells staged from unstaged changes by the index (X) and
+// work tree (Y) columns.
+func getChangedStatus(m string) status {
+	inIndex := m[0] != ' '
+	inWorkTree := m[1] != ' '
+
+	switch {
+	case inIndex && inWorkTree:
+		return FileStatusPartiallyStaged
+	case inIndex:
+		return FileStatusStaged
+	default:
+		return FileStatusChanged
+	}
+}
+
+// restore restores a deleted file from the index, or from HEAD if its deletion
+// is staged already.
+func restore(dir, path string) error {
+	if err := runGit(dir, "checkout", "--", path); err == nil {
+		return nil
+	}
+
+	return runGit(dir, "checkout", "HEAD", "--", path)
+}
+
+// revExists reports whether rev, e.g. "HEAD:./file", names an object.
+func revExists(dir, rev string) bool {
+	return runGit(dir, "cat-file", "-e", rev) == nil
+}
+
+func stage(dir, path string) error {
+	return runGit(dir, "add", "--all", "--", path)
+}
+
+func unstage(dir, path string) error {
+	return runGit(dir, "reset", "--quiet", "--", path)
+}
+
+// discard resets the work tree changes of path to the index.
+func discard(dir, path string) error {
+	return runGit(dir, "checkout", "--", path)
+}
+
+// runGit runs git in dir and returns its output as error if it fails.
+func runGit(dir string, args ...string) error {
+	cmd := git(args...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+
+	return nil
+}
+
 // TODO use go-git/go-git instead of spawning a process?
 func git(args ...string) *exec.Cmd {
 	cmd := exec.Command("git", args...)
@@ -0,0 +1,155 @@
+package files
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Output captured from `git status --porcelain=v2 -z --ignored` in a test
+// repository. Every entry is terminated by a NUL.
+const (
+	statusModified        = "1 .M N... 100644 100644 100644 78981922613b2afb6025042ff6bd878ac1994e85 78981922613b2afb6025042ff6bd878ac1994e85 mod.txt\x00"
+	statusStaged          = "1 M. N... 100644 100644 100644 397b4a7624e35fa60563a9c03b1213d93f7b6546 5f19ed5a1ecc6785288b5b8c578bbb05221b4d33 .gitignore\x00"
+	statusPartial         = "1 MM N... 100644 100644 100644 78981922613b2afb6025042ff6bd878ac1994e85 c4e5f68ff91104bc4da15441f1d4d479e4886c55 mod.txt\x00"
+	statusRenamed         = "2 R. N... 100644 100644 100644 61780798228d17af2d34fce4cfbdf35556832472 61780798228d17af2d34fce4cfbdf35556832472 R100 new name.txt\x00old name.txt\x00"
+	statusUnmerged        = "u UU N... 100644 100644 100644 100644 f2ad6c76f0115a6ba5b00456a849810e7ec0af20 ba2906d0666cf726c7eaadd2cd3db615dedfdf3a e45c9c2666d44e0327c1f9c239a74c508336053e conflict.txt\x00"
+	statusSubmodule       = "1 .M SC.. 160000 160000 160000 25a901303ed1e6054c71574f4f564b30101a0457 25a901303ed1e6054c71574f4f564b30101a0457 sub\x00"
+	statusDeleted         = "1 .D N... 100644 100644 000000 d905d9da82c97264ab6f4920e20242e088850ce9 d905d9da82c97264ab6f4920e20242e088850ce9 b c\x00"
+	statusRemoved         = "1 D. N... 100644 000000 000000 4bcfe98e640c8284511312660fb8709b0afa888e 0000000000000000000000000000000000000000 dir/a\x00"
+	statusModifiedDeleted = "1 MD N... 100644 100644 000000 587be6b4c3f93f93c489c0111bba5596147a26cb b77b4eb1d946f923f61785536da9ca5af6909f06 dir/md\x00"
+	statusUntracked       = "? ünïcødé.txt\x00"
+	statusNewline         = "? line\nbreak.txt\x00"
+	statusIgnored         = "! build/\x00! debug.log\x00"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want statusMap
+	}{
+		{
+			name: "empty",
+			out:  "",
+			want: statusMap{},
+		},
+		{
+			name: "modified in work tree",
+			out:  statusModified,
+			want: statusMap{"/repo/mod.txt": {FileStatusChanged, " M"}},
+		},
+		{
+			name: "modified in index",
+			out:  statusStaged,
+			want: statusMap{"/repo/.gitignore": {FileStatusStaged, "M "}},
+		},
+		{
+			name: "modified in index and work tree",
+			out:  statusPartial,
+			want: statusMap{"/repo/mod.txt": {FileStatusPartiallyStaged, "MM"}},
+		},
+		{
+			name: "rename with original path entry",
+			out:  statusRenamed + statusModified,
+			want: statusMap{
+				"/repo/new name.txt": {FileStatusStaged, "R "},
+				"/repo/mod.txt":      {FileStatusChanged, " M"},
+			},
+		},
+		{
+			name: "unmerged",
+			out:  statusUnmerged,
+			want: statusMap{"/repo/conflict.txt": {FileStatusConflicted, "UU"}},
+		},
+		{
+			name: "submodule",
+			out:  statusSubmodule,
+			want: statusMap{"/repo/sub": {FileStatusChanged, " M"}},
+		},
+		{
+			name: "deleted in work tree and index",
+			out:  statusDeleted + statusRemoved,
+			want: statusMap{
+				"/repo/b c":   {FileStatusDeleted, " D"},
+				"/repo/dir/a": {FileStatusDeleted, "D "},
+			},
+		},
+		{
+			name: "untracked with unicode and newline",
+			out:  statusUntracked + statusNewline,
+			want: statusMap{
+				"/repo/ünïcødé.txt":     {FileStatusUntracked, "??"},
+				"/repo/line\nbreak.txt": {FileStatusUntracked, "??"},
+			},
+		},
+		{
+			name: "ignored",
+			out:  statusIgnored,
+			want: statusMap{
+				"/repo/debug.log": {FileStatusIgnored, "!!"},
+				"/repo/build":     {FileStatusIgnored, "!!"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseStatus("/repo", test.out); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseStatus() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStatusMapDeletionStaged(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		path string
+		want bool
+	}{
+		{"staged file", statusRemoved, "/repo/dir/a", true},
+		{"staged dir", statusRemoved, "/repo/dir", true},
+		{"unstaged file", statusDeleted, "/repo/b c", false},
+		{"modified in index", statusModifiedDeleted, "/repo/dir/md", false},
+		{"partly staged dir", statusRemoved + statusModifiedDeleted, "/repo/dir", false},
+		{"not deleted", statusModified, "/repo/mod.txt", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseStatus("/repo", test.out).deletionStaged(test.path); got != test.want {
+				t.Errorf("deletionStaged(%q) = %v, want %v", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestStatusMapGet(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		path string
+		dir  bool
+		want status
+	}{
+		{"file", statusModified, "/repo/mod.txt", false, FileStatusChanged},
+		{"unchanged file", statusModified, "/repo/other.txt", false, FileStatusNormal},
+		{"changed dir", statusModified, "/repo", true, FileStatusChanged},
+		{"staged dir", statusStaged, "/repo", true, FileStatusStaged},
+		{"staged deletion", statusRemoved, "/repo/dir", true, FileStatusStaged},
+		{"unstaged deletion", statusDeleted, "/repo", true, FileStatusChanged},
+		{"staged and unstaged", statusRemoved + statusDeleted, "/repo", true, FileStatusPartiallyStaged},
+		{"modified in index and deleted", statusModifiedDeleted, "/repo/dir", true, FileStatusPartiallyStaged},
+		{"conflict wins", statusUnmerged + statusStaged, "/repo", true, FileStatusConflicted},
+		{"untracked dir", statusUntracked, "/repo", true, FileStatusUntracked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseStatus("/repo", test.out).get(test.path, test.dir); got != test.want {
+				t.Errorf("get(%q) = %v, want %v", test.path, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package files
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// dataPath returns the path of a file in the plugin's data directory.
+func (p *FileProvider) dataPath(name string) string {
+	dir := ""
+	p.api.Eval("stdpath('data')", &dir)
+
+	return filepath.Join(dir, "tree", name)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so other instances never read a
+	// partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,140 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// trashDir returns the home trash as defined by the freedesktop.org trash
+// specification.
+func trashDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// topTrashDir returns the trash on the filesystem of path, $topdir/.Trash-$uid,
+// if path is not on the same filesystem as the home trash.
+func topTrashDir(path, homeTrash string) (string, bool) {
+	dev, ok := deviceID(path)
+	if !ok {
+		return "", false
+	}
+
+	if homeDev, ok := deviceID(existingParent(homeTrash)); !ok || homeDev == dev {
+		return "", false
+	}
+
+	top := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(top)
+		if parentDev, ok := deviceID(parent); parent == top || !ok || parentDev != dev {
+			break
+		}
+		top = parent
+	}
+
+	return filepath.Join(top, fmt.Sprintf(".Trash-%d", os.Getuid())), true
+}
+
+func existingParent(path string) string {
+	for !exists(path) && filepath.Dir(path) != path {
+		path = filepath.Dir(path)
+	}
+	return path
+}
+
+// trash moves path into the trash and returns its location in the trash.
+func trash(path string) (string, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return "", err
+	}
+
+	if top, ok := topTrashDir(path, dir); ok {
+		dir = top
+	}
+
+	filesDir := filepath.Join(dir, "files")
+	infoDir := filepath.Join(dir, "info")
+
+	for _, d := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	base := filepath.Base(path)
+
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d", base, n)
+		}
+
+		// The info file is created first, to claim the name in the trash
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		info, err := os.OpenFile(infoPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Skip names of orphaned files without an info file, the rename would
+		// replace them
+		trashPath := filepath.Join(filesDir, name)
+		if _, err := os.Lstat(trashPath); err == nil {
+			info.Close()
+			os.Remove(infoPath)
+			continue
+		}
+
+		_, err = fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: path}).EscapedPath(),
+			time.Now().Format("2006-01-02T15:04:05"),
+		)
+		info.Close()
+
+		if err == nil {
+			err = os.Rename(path, trashPath)
+		}
+
+		if err != nil {
+			os.Remove(infoPath)
+			if errors.Is(err, syscall.EXDEV) {
+				return "", fmt.Errorf("%s is on a different filesystem than the trash %s", path, dir)
+			}
+			return "", err
+		}
+
+		return trashPath, nil
+	}
+}
+
+// untrash moves trashPath out of the trash to path and removes its info file.
+func untrash(trashPath, path string) error {
+	if err := movePath(trashPath, path); err != nil {
+		return err
+	}
+
+	infoPath := filepath.Join(filepath.Dir(filepath.Dir(trashPath)), "info", filepath.Base(trashPath)+".trashinfo")
+	if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package files
+
+// deviceID is not supported on windows, files are always moved to the home
+// trash.
+func deviceID(path string) (uint64, bool) {
+	return 0, false
+}
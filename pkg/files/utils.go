@@ -1,6 +1,10 @@
 package files
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
 
 func childrenNames(path string) []string {
 	names := []string{}
@@ -11,22 +15,12 @@ func childrenNames(path string) []string {
 	}
 	defer file.Close()
 
-	list, _ := file.Readdirnames(0) // 0 to read all files and folders
-	for _, name := range list {
-		if !dummyIsIgnored(name) {
-			names = append(names, name)
-		}
-	}
+	names, _ = file.Readdirnames(0) // 0 to read all files and folders
 	return names
 }
 
-func dummyIsIgnored(name string) bool {
-	for _, i := range []string{".git", ".DS_Store"} {
-		if i == name {
-			return true
-		}
-	}
-	return false
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
 }
 
 func isDir(path string) bool {
@@ -37,3 +31,11 @@ func isDir(path string) bool {
 	return fi.Mode().IsDir()
 }
 
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func isBelow(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,43 @@
+package files
+
+import "time"
+
+const watchDelay = 100 * time.Millisecond
+
+// watcher reports changes to the entries of a set of directories.
+type watcher interface {
+	// set replaces the watched directories.
+	set(dirs []string)
+	// events receives a value whenever a watched directory changed. It is
+	// closed once the watcher is closed.
+	events() <-chan struct{}
+	close()
+}
+
+// debounce merges events that follow each other within delay.
+func debounce(in <-chan struct{}, delay time.Duration) <-chan struct{} {
+	out := make(chan struct{})
+
+	go func() {
+		defer close(out)
+
+		for range in {
+		wait:
+			for {
+				select {
+				case _, ok := <-in:
+					if !ok {
+						return
+					}
+
+				case <-time.After(delay):
+					break wait
+				}
+			}
+
+			out <- struct{}{}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package files
+
+import (
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE |
+	syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO |
+	syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE_SELF |
+	syscall.IN_MOVE_SELF |
+	syscall.IN_ONLYDIR
+
+type inotifyWatcher struct {
+	mu      sync.Mutex
+	fd      int
+	file    *os.File
+	watches map[string]int
+	ch      chan struct{}
+}
+
+func newWatcher() (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watches: map[string]int{},
+		ch:      make(chan struct{}, 1),
+	}
+
+	go w.read()
+
+	return w, nil
+}
+
+func (w *inotifyWatcher) set(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keep := map[string]bool{}
+
+	for _, dir := range dirs {
+		keep[dir] = true
+
+		if _, ok := w.watches[dir]; ok {
+			continue
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err != nil {
+			log.Printf("inotify: watch %s - err: %v", dir, err)
+			continue
+		}
+		w.watches[dir] = wd
+	}
+
+	for dir, wd := range w.watches {
+		if !keep[dir] {
+			// Fails for directories that are gone already, which is fine
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, dir)
+		}
+	}
+}
+
+func (w *inotifyWatcher) events() <-chan struct{} {
+	return w.ch
+}
+
+func (w *inotifyWatcher) close() {
+	w.file.Close()
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.ch)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		if hasChanges(buf[:n]) {
+			select {
+			case w.ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// hasChanges reports whether buf contains any event other than the removal
+// of a watch.
+func hasChanges(buf []byte) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		if event.Mask&syscall.IN_IGNORED == 0 {
+			return true
+		}
+		offset += syscall.SizeofInotifyEvent + int(event.Len)
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package files
+
+func newWatcher() (watcher, error) {
+	return newPollWatcher(), nil
+}
@@ -0,0 +1,106 @@
+package files
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	pollInterval = 1 * time.Second
+	// pollStatusInterval is the delay between two updates of the git status
+	// while polling, changed file contents do not show in the modification
+	// time of their directory.
+	pollStatusInterval = 5 * time.Second
+)
+
+// pollWatcher compares the modification times of the watched directories
+// every second. It is used on platforms without inotify, or if inotify is not
+// available.
+type pollWatcher struct {
+	mu   sync.Mutex
+	dirs map[string]time.Time
+
+	ch   chan struct{}
+	done chan struct{}
+}
+
+func newPollWatcher() watcher {
+	w := &pollWatcher{
+		dirs: map[string]time.Time{},
+		ch:   make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.ch)
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-time.After(pollInterval):
+			}
+
+			if !w.scan() {
+				continue
+			}
+
+			select {
+			case w.ch <- struct{}{}:
+			case <-w.done:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// scan reports whether a watched directory changed since the last scan.
+func (w *pollWatcher) scan() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := false
+	for dir, modTime := range w.dirs {
+		current := dirModTime(dir)
+		if !current.Equal(modTime) {
+			w.dirs[dir] = current
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (w *pollWatcher) set(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched := map[string]time.Time{}
+	for _, dir := range dirs {
+		if modTime, ok := w.dirs[dir]; ok {
+			watched[dir] = modTime
+		} else {
+			watched[dir] = dirModTime(dir)
+		}
+	}
+
+	w.dirs = watched
+}
+
+func (w *pollWatcher) events() <-chan struct{} {
+	return w.ch
+}
+
+func (w *pollWatcher) close() {
+	close(w.done)
+}
+
+func dirModTime(dir string) time.Time {
+	if fi, err := os.Stat(dir); err == nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,79 @@
+package opener
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/josa42/go-neovim"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+// RenameBuffers points every buffer showing path, or a file below it, to the
+// same file below target.
+func RenameBuffers(api *neovim.Api, path, target string) {
+	unsaved := []string{}
+
+	for _, b := range findBuffers(api, path) {
+		name := filepath.Join(target, strings.TrimPrefix(b.Path(), path))
+
+		api.Executef("call nvim_buf_set_name(%d, %s)", b.ID(), prompt.Quote(name))
+
+		// Setting the name marks the buffer as "not edited". Unmodified buffers
+		// are reloaded to clear the flag, modified ones keep their changes and
+		// need to be written with :write!
+		modified := 0
+		api.Eval(fmt.Sprintf("getbufvar(%d, '&modified')", b.ID()), &modified)
+		if modified != 0 {
+			unsaved = append(unsaved, filepath.Base(name))
+			continue
+		}
+
+		api.Executef("lua vim.api.nvim_buf_call(%d, function() vim.cmd('silent! edit!') end)", b.ID())
+	}
+
+	if len(unsaved) > 0 {
+		api.Out.Print(fmt.Sprintf("Unsaved changes in %s, use :write! to save them at the new location", strings.Join(unsaved, ", ")))
+	}
+}
+
+func findBuffers(api *neovim.Api, path string) []*neovim.Buffer {
+	buffers := []*neovim.Buffer{}
+
+	api.FindBuffer(func(b *neovim.Buffer) bool {
+		if bp := b.Path(); bp == path || strings.HasPrefix(bp, path+string(filepath.Separator)) {
+			buffers = append(buffers, b)
+		}
+		return false
+	})
+
+	return buffers
+}
+
+// WipeBuffers wipes every buffer showing path, or a file below it. Windows
+// showing such a buffer are kept open with an empty buffer.
+func WipeBuffers(api *neovim.Api, path string) {
+	for _, b := range findBuffers(api, path) {
+		api.Executef("for w in win_findbuf(%d) | call win_execute(w, 'silent! enew') | endfor", b.ID())
+		api.Executef("silent! bwipeout! %d", b.ID())
+	}
+}
+
+// ChangeDir changes the working directory with cmd ("cd", "tcd" or "lcd"). The
+// local directory is changed for the editor window, not for the tree. Without
+// an editor window in the tab the tab directory is changed instead.
+func ChangeDir(api *neovim.Api, cmd, path string) {
+	if cmd == "lcd" {
+		windows := []int{}
+		api.Eval("filter(gettabinfo(tabpagenr())[0].windows, {_, w -> !getbufvar(winbufnr(w), 'is_tree')})", &windows)
+
+		if len(windows) > 0 {
+			api.Executef("call win_execute(%d, 'lcd ' . fnameescape(%s))", windows[0], prompt.Quote(path))
+			return
+		}
+
+		cmd = "tcd"
+	}
+
+	api.Executef("execute %s . ' ' . fnameescape(%s)", prompt.Quote(cmd), prompt.Quote(path))
+}
@@ -0,0 +1,43 @@
+package opener
+
+import (
+	"strings"
+
+	"github.com/josa42/go-neovim"
+	"github.com/josa42/nvim-filetree/pkg/prompt"
+)
+
+// OpenCommandOutput shows the output of a command in a scratch split.
+func OpenCommandOutput(api *neovim.Api, args []string, filetype string) {
+	FocusEditor(api)
+
+	api.Execute("silent botright new")
+	readCommandOutput(api, args)
+	api.Executef("setlocal nomodifiable filetype=%s", filetype)
+}
+
+// DiffCommandOutput opens path and diffs it against the output of a command
+// in a vertical split.
+func DiffCommandOutput(api *neovim.Api, path string, args []string) {
+	Open(api, path)
+	api.Execute("diffthis")
+
+	filetype := ""
+	api.Eval("&filetype", &filetype)
+
+	api.Execute("silent leftabove vnew")
+	readCommandOutput(api, args)
+	api.Executef("setlocal nomodifiable filetype=%s", filetype)
+	api.Execute("diffthis")
+}
+
+func readCommandOutput(api *neovim.Api, args []string) {
+	quoted := make([]string, len(args))
+	for idx, a := range args {
+		quoted[idx] = prompt.Quote(a)
+	}
+
+	api.Execute("setlocal buftype=nofile bufhidden=wipe noswapfile")
+	api.Executef("execute 'silent read !' . join(map([%s], {_, a -> shellescape(a, 1)}), ' ')", strings.Join(quoted, ", "))
+	api.Execute("silent 1delete _")
+}
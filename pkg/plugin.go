@@ -29,6 +29,7 @@ const (
 	GlobalVarTreeBufferID  = "tree_buffer_id"
 	GlobalVarIsTreeOpen    = "tree_open"
 	GlobalVarIsTreeOpening = "tree_opening"
+	GlobalVarFollow        = "tree_follow"
 )
 
 var (
@@ -41,8 +42,9 @@ type Tree interface {
 }
 
 type TreePlugin struct {
-	api      *neovim.Api
-	treeView *view.TreeView
+	api          *neovim.Api
+	treeView     *view.TreeView
+	fileProvider *files.FileProvider
 }
 
 func (tp *TreePlugin) Register(api neovim.RegisterApi) {
@@ -57,17 +59,26 @@ func (tp *TreePlugin) Register(api neovim.RegisterApi) {
 	api.Function("TreeFocus", tp.Focus)
 	api.Function("TreeToggleFocus", tp.ToggleFocus)
 	api.Function("TreeToggleSmart", tp.ToggleSmart)
+	api.Function("TreeMarkRange", tp.MarkRange)
+	api.Function("TreeRefresh", tp.Refresh)
+	api.Function("TreeReveal", tp.Reveal)
+	api.Function("TreeSaveOpenDirs", tp.SaveOpenDirs)
 }
 
 func (tp *TreePlugin) Activate(api *neovim.Api) {
 	tp.api = api
 
-	tp.treeView = view.NewTreeView(files.NewFileProvider(api))
+	tp.fileProvider = files.NewFileProvider(api)
+	tp.treeView = view.NewTreeView(tp.fileProvider)
 
 	api.Global.On(neovim.EventBufWinEnter, tp.onEnterSyncState)
 	api.Global.On(neovim.EventWinEnter, tp.onEnterSyncState)
 	api.Global.On(neovim.EventBufEnter, tp.onLeaveCloseLastTree)
+	api.Global.On(neovim.EventBufEnter, tp.onEnterFollow)
 	api.Global.On(neovim.EventWinLeave, tp.onLeaveUnfocusTree)
+
+	api.Execute("augroup tree_refresh | autocmd! | autocmd DirChanged,BufWritePost,FocusGained * call TreeRefresh() | augroup END")
+	api.Execute("augroup tree_save | autocmd! | autocmd VimLeavePre * call TreeSaveOpenDirs() | augroup END")
 }
 
 func (p *TreePlugin) Close() {
@@ -77,6 +88,7 @@ func (p *TreePlugin) Close() {
 		}
 	}()
 	p.api.Global.Vars.SetBool(GlobalVarIsTreeOpen, false)
+	p.fileProvider.SaveOpenDirs()
 	if b, found := p.getTreeBuffer(); found {
 		b.Close()
 	}
@@ -252,6 +264,70 @@ func (p *TreePlugin) ToggleSmart() {
 	}
 }
 
+func (p *TreePlugin) MarkRange() {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("MarkRange() recover: %v\n", err)
+		}
+	}()
+
+	p.fileProvider.MarkRange()
+}
+
+func (p *TreePlugin) Refresh() {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("Refresh() recover: %v\n", err)
+		}
+	}()
+
+	p.fileProvider.Refresh()
+}
+
+// Reveal shows the file of the current buffer in the tree, without moving the
+// focus into the tree.
+func (p *TreePlugin) Reveal() {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("Reveal() recover: %v\n", err)
+		}
+	}()
+
+	if p.ignoreCurrentTab() || p.treeBufferHasFocus() {
+		return
+	}
+
+	path := p.api.CurrentBuffer().Path()
+
+	if !p.hasTreeBuffer() {
+		p.Open()
+		p.Unfocus()
+	}
+
+	p.fileProvider.Reveal(path)
+}
+
+// SaveOpenDirs stores the open directories before Neovim exits.
+func (p *TreePlugin) SaveOpenDirs() {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("SaveOpenDirs() recover: %v\n", err)
+		}
+	}()
+
+	p.fileProvider.SaveOpenDirs()
+}
+
+func (p *TreePlugin) onEnterFollow() {
+	if !p.api.Global.Vars.Bool(GlobalVarFollow) || p.api.Global.Vars.Bool(GlobalVarIsTreeOpening) {
+		return
+	}
+
+	if p.hasTreeBuffer() && !p.treeBufferHasFocus() {
+		p.fileProvider.Reveal(p.api.CurrentBuffer().Path())
+	}
+}
+
 func (p *TreePlugin) getOrCreateBuffer() *neovim.Buffer {
 	if b, ok := p.getTreeBuffer(); ok {
 		return b
@@ -273,7 +349,8 @@ func (p *TreePlugin) createTreeBuffer() *neovim.Buffer {
 	buffer.Vars.SetBool(BufferVarIsTree, true)
 	buffer.Vars.SetBool(BufferVarHideLightline, true)
 	buffer.Options.SetFileType("tree")
-	buffer.SetTitle("פּ")
+	p.fileProvider.ApplySyntax(buffer.ID())
+	buffer.SetTitle(p.fileProvider.Title())
 
 	p.api.Executef("setlocal %s", strings.Join([]string{
 		"cursorline",
@@ -290,6 +367,7 @@ func (p *TreePlugin) createTreeBuffer() *neovim.Buffer {
 		"colorcolumn=",
 	}, " "))
 	p.api.Execute("iabclear <buffer>")
+	p.api.Execute("xnoremap <buffer><silent> m :<C-u>call TreeMarkRange()<CR>")
 	p.api.Execute("set winhighlight=Normal:TreeNormal")
 
 	p.api.Renderer.Attach(buffer, p.treeView)
@@ -0,0 +1,52 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/josa42/go-neovim"
+)
+
+// Input asks for a line of text, prefilled with text. The result is false if
+// the prompt was cancelled or left empty.
+func Input(api *neovim.Api, message, text string) (string, bool) {
+	result := ""
+	api.Eval(fmt.Sprintf("input(%s, %s, 'file')", Quote(message), Quote(text)), &result)
+
+	result = strings.TrimSpace(result)
+
+	return result, result != ""
+}
+
+// Quote returns s as a single quoted vim script string.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Confirm asks a yes or no question, defaulting to no.
+func Confirm(api *neovim.Api, message string) bool {
+	return Choose(api, message, []string{"&Yes", "&No"}, 2) == 1
+}
+
+// Choose asks to pick one of choices and returns its 1-based index, or 0 if
+// the prompt was cancelled.
+func Choose(api *neovim.Api, message string, choices []string, def int) int {
+	choice := 0
+	api.Eval(fmt.Sprintf(`confirm(%s, "%s", %d)`, Quote(message), strings.Join(choices, `\n`), def), &choice)
+
+	return choice
+}
+
+// Select lists items below title and returns the 1-based index of the chosen
+// one, or 0 if the prompt was cancelled.
+func Select(api *neovim.Api, title string, items []string) int {
+	lines := []string{Quote(title)}
+	for idx, item := range items {
+		lines = append(lines, Quote(fmt.Sprintf("%d. %s", idx+1, item)))
+	}
+
+	choice := 0
+	api.Eval(fmt.Sprintf("inputlist([%s])", strings.Join(lines, ", ")), &choice)
+
+	return choice
+}
@@ -26,7 +26,11 @@ call remote#host#RegisterPlugin('tree', '0', [
 \ {'type': 'function', 'name': 'OperatorFunc_2f4eab2fca750d49cc9039bac724b89b', 'sync': 1, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeClose', 'sync': 0, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeFocus', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'TreeMarkRange', 'sync': 0, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeOpen', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'TreeRefresh', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'TreeReveal', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'TreeSaveOpenDirs', 'sync': 1, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeToggle', 'sync': 0, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeToggleFocus', 'sync': 0, 'opts': {}},
 \ {'type': 'function', 'name': 'TreeToggleSmart', 'sync': 0, 'opts': {}},
@@ -1,16 +1,16 @@
 syn match TreeIcon     /\(^\(  \)*. \)\@<=[^ ]/
-syn match TreeDirIcon  /[ﱮ▸▾•]/ containedin=TreeIcon
-syn match TreeFileIcon /[•]/    containedin=TreeIcon
 
-syn match TreeName     /\(^\(  \)*. [ﱮ▸▾•] \)\@<=.*$/
-syn match TreeDirName  /\(^\(  \)*. [ﱮ▸▾•] \)\@<=.*$/
-syn match TreeFileName /\(^\(  \)*. [•] \)\@<=.*$/
+syn match TreeName     /\(^\(  \)*. [^ ] \)\@<=.*$/
 syn match TreeDirSlash #/# containedin=TreeName,TreeDirName
+syn match TreeDetails  / \{2,}\S\+ \+\S\+ \+[-dl][-rwx]\{9}$/ containedin=TreeName,TreeDirName,TreeFileName
 
-syn match TreeStatus            /\(^\(  \)*\)\@<=[^ ]\([^ ] \)\@=/
-syn match TreeStatusChanged     /\(^\(  \)*\)\@<=◎/  containedin=TreeStatus
-syn match TreeStatusAdded       /\(^\(  \)*\)\@<=⦿/  containedin=TreeStatus
-syn match TreeStatusConcflicted /\(^\(  \)*\)\@<=◉/  containedin=TreeStatus
+syn match TreeStatus   /\(^\(  \)*\)\@<=[^ ]\([^ ] \)\@=/
+
+" The icons, the status glyphs and the mark depend on the icon theme, the
+" plugin stores their rules in b:tree_syntax
+for s:rule in get(b:, 'tree_syntax', [])
+  execute s:rule
+endfor
 
 " Default theme
 highlight default link TreeNormal    Normal
@@ -18,9 +18,16 @@ highlight default link TreeFileIcon  TreeNormal
 highlight default link TreeDirIcon   Directory
 highlight default link TreeDirSlash  Comment
 highlight default link TreeDirName   Directory
+highlight default link TreeMarked    Special
+highlight default link TreeDetails   Comment
+highlight default link TreeFilterMatch Search
 
 highlight default link TreeStatus            Comment
 highlight default link TreeStatusChanged     TreeStatus
 highlight default link TreeStatusAdded       TreeStatus
 highlight default link TreeStatusConcflicted Error
+highlight default link TreeStatusStaged      String
+highlight default link TreeStatusPartial     WarningMsg
+highlight default link TreeGhost             Comment
+highlight default link TreeIgnored           NonText
 